@@ -8,6 +8,7 @@ import (
 	"magpie/parser"
 	"os"
 	"runtime"
+	"strconv"
 )
 
 /*
@@ -152,11 +153,337 @@ func TestEval() {
 		{`a, b, c, d = 1, true, "hello", 12.343678; printf("a=%g, b=%t, c=%s, d=%.2f\n", a, b, c,d)`, "nil"},
 		{`printf("2**3=%g, 2.34.floor=%.0f\n", 2.pow(3), 2.34.floor())`, "nil"},
 
+		//integer
+		{`9007199254740993`, "9007199254740993"},
+		{`9007199254740993 + 1`, "9007199254740994"},
+		{`7 / 2`, "3"},
+		{`7 % 3`, "1"},
+		{`7 / 2.0`, "3.5"},
+		{`type(7)`, "integer"},
+		{`type(7.0)`, "number"},
+		{`2 ** -1`, "0.5"},
+		{`1 / 0`, "error"},
+		{`x = 10; x /= 4; x`, "2"},
+		{`h = {1: "one"}; h[1.0]`, "one"},
+		{`a = [1, 2, 3]; [a[1.0], a[-1.0]]`, "[2, 3]"},
+		{`a = [1, 2, 3]; a[1.5]`, "error"},
+		{`a = [1, 2, 3]; a[1e30]`, "error"},
+		{`strconv.ParseUint("18446744073709551615", 10, 64)[0]`, "18446744073709551615"},
+		{`h = {2.0 ** 70: "big"}; h[2 ** 70]`, "big"},
+		{`[(2.7).int(), (-2.7).int(), (2.0 ** 70).str(16)]`, `[2, -2, "400000000000000000"]`},
+		{`(1e30).int()`, "error"},
+		{`(1e308 * 10).int()`, "error"},
+		{`printf("%d %5.2f %s\n", 42, 42, 42)`, "nil"},
+
+		//bigint & decimal
+		{`123n * 2`, "246"},
+		{`type(123n)`, "bigint"},
+		{`9223372036854775807 + 1`, "9223372036854775808"},
+		{`(-9223372036854775807 - 1) / -1`, "9223372036854775808"},
+		{`-(-9223372036854775807 - 1) - 1`, "9223372036854775807"},
+		{`(-9223372036854775807 - 1) % -1`, "0"},
+		{`2 ** 64`, "18446744073709551616"},
+		{`0.1d + 0.2d`, "0.3"},
+		{`0.1d + 0.2d == 0.3d`, "true"},
+		{`12.30d`, "12.30"},
+		{`10.00d / 4`, "2.50"},
+		{`1.5d + 1`, "2.5"},
+		{`1n < 2 < 3.5d`, "true"},
+		{`h = {1: "one"}; h[1n]`, "one"},
+		{`1n / 0`, "error"},
+		{`printf("%d %.2f\n", 2n ** 70, 19.999d)`, "nil"},
+
+		//number literals
+		{`0xFF`, "255"},
+		{`0xad + 0x1d + 0xdd`, "423"},
+		{`0xffn + 0x0d`, "268"},
+		{`0o755`, "493"},
+		{`0b1010`, "10"},
+		{`1_000_000`, "1000000"},
+		{`1.5e-3`, "0.0015"},
+		{`6.02E23`, "6.02e+23"},
+		{`0xFFn * 2`, "510"},
+		{`1.5e3d`, "1500"},
+		{`255.str(16)`, "ff"},
+		{`10.str(2)`, "1010"},
+		{`10.str(37)`, "error"},
+
+		//bitwise operators
+		{`6 & 3`, "2"},
+		{`6 | 3`, "7"},
+		{`6 ^ 3`, "5"},
+		{`~5`, "-6"},
+		{`1 << 10`, "1024"},
+		{`-16 >> 2`, "-4"},
+		{`1 << 64`, "18446744073709551616"},
+		{`1 | 2 ^ 3 & 4`, "3"},
+		{`1 + 2 << 1`, "6"},
+		{`6 & 3 == 2`, "true"},
+		{`x = 0b1100; x &= 0b1010; x |= 1; x ^= 0xFF; x <<= 2; x >>= 3; x`, "123"},
+		{`1 << -1`, "error"},
+		{`1.5 & 1`, "error"},
+
+		//raw & multi-line strings
+		{`r"C:\new\table"`, `C:\new\table`},
+		{"\"\"\"\na\n    b\n\"\"\"", "a\n    b"},
+		{"\"\"\"\n  a\n      b\n    c\n  \"\"\"", "a\n    b\n  c"},
+		{"\"\"\"\n    a\n\n    b\n\"\"\"", "a\n\nb"},
+		{`name = "x"; r"$name"`, "$name"},
+		{`len(r"\d+")`, "3"},
+		{"s = \"\"\"\n    a\n      b\n    \"\"\"; s", "a\n  b"},
+		{"s = r\"\"\"  a\n  b\"\"\"; s", "  a\n  b"},
+
+		//escape sequences
+		{`"\u4e2d\u6587"`, "中文"},
+		{`"\U0001F600"`, "\U0001F600"},
+		{`"\x41\101"`, "AA"},
+		{`len("\0")`, "1"},
+		{`len("\x1b[31m")`, "5"},
+		{`"a\tb\\c\"d"`, "a\tb\\c\"d"},
+
+		//string interpolation
+		{`a = 3; b = 4; "${a} + $b = ${a + b}"`, "3 + 4 = 7"},
+		{`h = {"k": "v"}; arr = [1, 2]; "${h["k"]}, ${arr[1]}, ${len(arr)}"`, "v, 2, 2"},
+		{`price = 3.14159; "${price:.2f}|${10:5d}|${255:x}"`, "3.14|   10|ff"},
+		{`name = "x"; "\$name, ${name"`, "$name, ${name"},
+		{`x = 1; "$if $for $x $true"`, "$if $for 1 true"},
+		{`name = "x"; "C:\\$name, \${name}"`, `C:\x, ${name}`},
+		{`name = "x"; len("\0$name\x00")`, "3"},
+		{`"${undefinedVar}"`, "error"},
+
 		// &&, ||
 		{`if 10 == 10 && 10 > 5 { printf("10 == 10 && 10 > 5\n")}`, "nil"},
 		{`if 10 == 10 && 10 > 12 { printf("10 == 10 && 10 > 12\n") } else { println("10 not larger than 12") }`, "nil"},
 		{`if 10 == 10 || 10 > 12 { printf("10 == 10 || 10 > 12\n")}`, "nil"},
 		{`if 10 == 11 || 10 > 12 { printf("10 == 11 || 10 > 12\n") } else { println(" 10 not equal 11 and 10 not larger than 12") }`, "nil"},
+		{`x = nil; if x != nil && x.foo() { println("x.foo()") } else { println("x is nil") }`, "nil"},
+		{`opt = nil; name = opt || "default"; name`, "default"},
+		{`v = 0 && 10; v`, "0"},
+		{`v = 5 && "hello"; v`, "hello"},
+
+		//ternary, null-coalescing
+		{`a = 3; b = 4; a > b ? a : b`, "4"},
+		{`x = 5; x < 0 ? "neg" : x == 0 ? "zero" : "pos"`, "pos"},
+		{`true ? 1 : undefinedVar`, "1"},
+		{`x = nil; x ?? "default"`, "default"},
+		{`x = 0; x ?? 10`, "0"},
+		{`nil ?? nil ?? 3`, "3"},
+		{`1 ?? undefinedVar`, "1"},
+		{`x = nil; x ?? 1 + 2`, "3"},
+		{`r = 1 < 2 ? 1..3 : 4..6; r`, "[1, 2, 3]"},
+		{`2 in [1, 2] ? "yes" : "no"`, "yes"},
+
+		//optional chaining
+		{`h = {"a": {"b": 1}}; h.a?.b`, "1"},
+		{`h = {"a": {"b": 1}}; h.x?.b`, "nil"},
+		{`h = {"a": [1, 2]}; h["a"]?[1]`, "2"},
+		{`h = {"a": [1, 2]}; h["x"]?[1]`, "nil"},
+		{`t = nil; t?[0]`, "nil"},
+		{`x = nil; x?.foo(undefinedVar)`, "nil"},
+		{`x = nil; x?.name ?? "anonymous"`, "anonymous"},
+		{`x = nil; x?.a.b`, "error"},
+
+		//default parameters, keyword arguments
+		{`fn connect(host, port = 8080, timeout = 30) { "${host}:${port}/${timeout}" }; connect("db")`, "db:8080/30"},
+		{`fn connect(host, port = 8080, timeout = 30) { "${host}:${port}/${timeout}" }; connect("db", timeout: 5)`, "db:8080/5"},
+		{`fn connect(host, port = 8080, timeout = 30) { "${host}:${port}/${timeout}" }; connect(port: 1, host: "h")`, "h:1/30"},
+		{`fn f(x, y = x * 2) { x + y }; f(3)`, "9"},
+		{`add = (a, b = 10) => a + b; add(1)`, "11"},
+		{"struct P { fn init(x, y = 2) { self.x = x; self.y = y } }\np = P(1, y: 5); p.y", "5"},
+		{`fn f(a) { a }; f(b: 1)`, "error"},
+		{`fn f(a) { a }; f(1, a: 2)`, "error"},
+		{`fn f(a, b, rest...) { b }; f(1, b: 2)`, "2"},
+		{`fn f(a, b = 5, rest...) { a + b }; f(a: 1)`, "6"},
+		{`fn f(a, b, rest...) { len(rest) }; f(1, 2, 3, b: 4)`, "error"},
+		{`len([1], x: 1)`, "error"},
+
+		//arity checking
+		{`fn f(a, b) { a + b }; f(1)`, "error"},
+		{`fn f(a, b) { a + b }; f(1, 2, 3)`, "error"},
+		{`fn f(a, b = 2) { a + b }; f()`, "error"},
+		{`fn f(a, rest...) { len(rest) }; f()`, "error"},
+		{`fn f(a, rest...) { len(rest) }; f(1)`, "0"},
+		{`fn f(a, rest...) { len(rest) }; f(1, 2, 3)`, "2"},
+		{"struct P { fn init(x) { self.x = x } }\nP()", "error"},
+		{`fn f(n, acc) { if n == 0 { return acc } tailcall f(n - 1) }; f(3, 0)`, "error"},
+
+		//destructuring
+		{`let [first, second, ...rest] = [1, 2, 3, 4]; rest`, "[3, 4]"},
+		{`let [a, [b, c]] = [1, [2, 3]]; a + b + c`, "6"},
+		{`let {name, age: years} = {"name": "Bob", "age": 30}; "${name}/${years}"`, "Bob/30"},
+		{`let {name, ...others} = {"name": "Bob", "age": 30}; others`, `{"age":30}`},
+		{`let [_, x, ..._] = [1, 2, 3, 4]; x`, "2"},
+		{`fn f((x, y)) { x * y }; f((3, 4))`, "12"},
+		{`s = 0; for (k, [a, b]) in {"x": [1, 2], "y": [3, 4]} { s += a * b }; s`, "14"},
+		{`s = 0; for [a, b] in [[1, 2], [3, 4]] { s += a - b }; s`, "-2"},
+		{`a = 1; b = 2; [a, b] = [b, a]; "${a}${b}"`, "21"},
+		{`h = {"name": "x", "age": 3}; {name, age: years} = h; "${name}/${years}"`, "x/3"},
+		{`{a, "k": [_, b], ...rest} = {"a": 1, "k": [2, 3], "c": 4}; "${a}${b}${rest}"`, `13{"c":4}`},
+		{`x = 1; { x = 2 } x`, "2"},
+		{`const name = 1; {name} = {"name": 2}`, "error"},
+		{`{name} = [1]`, "error"},
+		{`let [a, b] = [1, 2, 3]`, "error"},
+		{`let {a} = {"b": 1}`, "error"},
+		{`fn f((x, y)) { x }; f(5)`, "error"},
+
+		//slices, negative indices
+		{`a = [0, 1, 2, 3, 4]; a[1:3]`, "[1, 2]"},
+		{`a = [0, 1, 2, 3, 4]; a[::-2]`, "[4, 2, 0]"},
+		{`a = [0, 1, 2, 3, 4]; a[-2:]`, "[3, 4]"},
+		{`a = [0, 1, 2, 3, 4]; a[-1]`, "4"},
+		{`a = [0, 1, 2]; a[5:]`, "[]"},
+		{`"hello"[1:-1]`, "ell"},
+		{`"hello"[::-1]`, "olleh"},
+		{`(1, 2, 3)[:2]`, "(1, 2)"},
+		{`a = [0, 1, 2, 3]; a[1:3] = ["x"]; a`, `[0, "x", 3]`},
+		{`a = [0, 1, 2, 3]; a[::2] = [8, 9]; a`, "[8, 1, 9, 3]"},
+		{`a = [0, 1, 2]; a[-1] = 5; a`, "[0, 1, 5]"},
+		{`a = [0, 1, 2]; a[-4]`, "error"},
+		{`a = [0, 1, 2]; a[::0]`, "error"},
+		{`a = [0, 1, 2, 3]; a[::2] = [1]`, "error"},
+		{`s = "hello"; s[1:3] = "XY"; s`, "hXYlo"},
+		{`s = "hello"; s[1:4] = ""; s`, "ho"},
+		{`s = "你好世界"; s[::2] = "ab"; s`, "a好b界"},
+		{`s = "hello"; s[::2] = "ab"`, "error"},
+		{`s = "hello"; s[1:3] = 1`, "error"},
+		{`t = (1, 2, 3); t[0:1] = [9]`, "error"},
+		{`h = {1: 2}; h[1:2] = 3`, "error"},
+
+		//spread
+		{`a = [1, 2]; b = (4, 5); [...a, 3, ...b]`, "[1, 2, 3, 4, 5]"},
+		{`a = [1, 2]; (0, ...a)`, "(0, 1, 2)"},
+		{`[..."abc"]`, `["a", "b", "c"]`},
+		{`fn f(x, y, z) { x * 100 + y * 10 + z }; xs = [2, 3]; f(1, ...xs)`, "123"},
+		{`fn f(x, y, z) { x * 100 + y * 10 + z }; xs = [1]; f(...xs, 2, 3)`, "123"},
+		{`d = @{"a": 1, "b": 2}; @{...d, "b": 3, "c": 4}`, `{"a":1, "b":3, "c":4}`},
+		{`d = @{"a": 1, "b": 2}; @{"b": 0, ...d}`, `{"b":2, "a":1}`},
+		{`[first, ...rest] = [1, 2, 3]; rest`, "[2, 3]"},
+		{`[...5]`, "error"},
+		{`h = {...[1]}`, "error"},
+
+		//comprehensions
+		{`xs = [-1, 2, 3, -4]; [x * 2 for x in xs if x > 0]`, "[4, 6]"},
+		{`[(x, y) for x in 1..2 for y in ["a", "b"]]`, `[(1, "a"), (1, "b"), (2, "a"), (2, "b")]`},
+		{`[[i * j for j in 1..2] for i in 1..2]`, "[[1, 2], [2, 4]]"},
+		{`h = @{"a": 1, "b": 2, "c": 3}; r = @{k: v * 10 for k, v in h if v != 2}; r`, `{"a":10, "c":30}`},
+		{`r = @{v: i for i, v in ["x", "y"]}; r`, `{"x":0, "y":1}`},
+		{`[a + b for [a, b] in [[1, 2], [3, 4]]]`, "[3, 7]"},
+		{`x = 100; ys = [x for x in [1, 2]]; x`, "100"},
+		{`[x for x in 5]`, "error"},
+
+		//pattern matching in switch
+		{`fn f(v) { switch v { case [x, y] if x > y { return x } case [x, y] { return y } } }; f([3, 1])`, "3"},
+		{`fn f(v) { switch v { case [x, y] if x > y { return x } case [x, y] { return y } } }; f([1, 3])`, "3"},
+		{`fn f(v) { switch v { case [_, ...rest] { return rest } } }; f((1, 2, 3))`, "[2, 3]"},
+		{`fn f(v) { switch v { case {"type": "add", "value": n} { return n + 1 } default { return 0 } } }; f({"type": "add", "value": 41})`, "42"},
+		{`fn f(v) { switch v { case {"type": "add", "value": n} { return n + 1 } default { return 0 } } }; f({"type": "sub", "value": 41})`, "0"},
+		{`struct P { fn init(x, y) { self.x = x; self.y = y } } fn f(v) { switch v { case P(0, y) { return y } case P(x: x) { return x } } }; f(P(2, 5))`, "2"},
+		{`struct P { fn init(x, y) { self.x = x; self.y = y } } fn f(v) { switch v { case P(0, y) { return y } case P(x: x) { return x } } }; f(P(0, 5))`, "5"},
+		{`fn f(v) { switch v { case /^(\w+)@(?P<host>\w+)$/ { return $1 + "/" + host } } }; f("bob@home")`, "bob/home"},
+		{`fn f(v) { switch v { case 1..9 { return "digit" } case 10..99 { return "number" } } }; f(42)`, "number"},
+		{`x = "outer"; switch [1] { case [x] { println(x) } }; x`, "outer"},
+		{`const K = 1; switch [2] { case [K] { K } }`, "error"},
+		{`const K = 1; fn f(v) { switch v { case [_, k] { return k } } }; f([1, 2]) + K`, "3"},
+		{`r = 0; switch 2 { case 1..3 { r += 1; fallthrough } case 9 { r += 10 } }; r`, "11"},
+		{`struct P { fn init(x) { self.x = x } } switch P(1) { case P(a, b) { println(a) } }`, "error"},
+		{`struct P { fn init(n) { self._n = n } } fn f(v) { switch v { case P(_n: n) { return n } } }; f(P(1))`, "error"},
+		{`struct P { fn init(_n) { self._n = _n } } fn f(v) { switch v { case P(n) { return n } } }; f(P(1))`, "error"},
+		{`struct P { fn init(n) { self._n = n } fn Same(o) { switch o { case P(_n: n) { return n == self._n } } } } P(1).Same(P(1))`, "true"},
+
+		//defer
+		{`log = []; fn f() { defer log.push("deferred"); log.push("body"); return log }; f()`, `["body", "deferred"]`},
+		{`log = []; fn f() { for i in 1..3 { defer log.push(i) } }; f(); log`, "[3, 2, 1]"},
+		{`fn f() { defer fn() { $result = $result * 2 }(); return 21 }; f()`, "42"},
+		{`log = []; fn f() { defer log.push("cleanup"); throw "oops" }; try { f() } catch e { log.push(e) } log`, `["cleanup", "oops"]`},
+		{`log = []; fn f(n) { defer log.push(n); if n == 0 { return "done" }; tailcall f(n - 1) }; f(2); log`, "[0, 1, 2]"},
+		{`defer println("top level")`, "error"},
+
+		//constants and freeze
+		{`const PI = 3.14; PI * 2`, "6.28"},
+		{`const (x, y) = (1, 2); x + y`, "3"},
+		{`fn f() { let N = 2; return N }; const N = 1; f() + N`, "3"},
+		{`const N = 1; N = 2`, "error"},
+		{`const N = 1; N += 2`, "error"},
+		{`const N = 1; N++`, "error"},
+		{`const N = 1; N--`, "error"},
+		{`N = 1; N++; N`, "2"},
+		{`const N = 1; fn f() { N = 2 }; f()`, "error"},
+		{`const N = 1; let N = 2`, "error"},
+		{`stdout = 1`, "error"},
+		{`arr = freeze([1, [2, 3]]); arr.len()`, "2"},
+		{`arr = freeze([1, [2, 3]]); arr[0] = 5`, "error"},
+		{`arr = freeze([1, [2, 3]]); inner = arr[1]; inner.push(4)`, "error"},
+		{`h = freeze({"a": 1}); h["b"] = 2`, "error"},
+		{`struct P { fn init(x) { self.x = x } } p = freeze(P(1)); p.x = 2`, "error"},
+
+		//enum
+		{`enum Color { Red, Green, Blue = 10, Purple } Color`, "enum Color { Red = 0, Green = 1, Blue = 10, Purple = 11 }"},
+		{`enum C {} C`, "enum C {}"},
+		{`enum Color { Red, Green, Blue = 10, Purple } Color.Purple.value`, "11"},
+		{`enum Color { Red, Green, Blue = 10, Purple } Color.Blue.ordinal`, "2"},
+		{`enum Color { Red, Green, Blue = 10, Purple } Color.Green.name`, "Green"},
+		{`enum Color { Red, Green, Blue = 10, Purple } [c.value for c in Color]`, "[0, 1, 10, 11]"},
+		{`enum Color { Red, Green } (Color.Red in Color, "Green" in Color, "Pink" in Color)`, "(true, true, false)"},
+		{`enum Color { Red, Green } h = {Color.Red: "stop", Color.Green: "go"}; h[Color.Green]`, "go"},
+		{`enum Color { Red, Green } c = Color.Green; switch c { case Color.Red { r = 1 } case Color.Green { r = 2 } }; r`, "2"},
+		{`enum Color { Red, Green = 5 } Color.from(5)`, "Color.Green"},
+		{`enum Color { Red, Green } Color.Pink`, "error"},
+		{`enum Color { Red = "r" }`, "error"},
+		{`enum Color { Red } Color = 1`, "error"},
+		{`struct A { fn init(x) { self.x = x } fn Get() { return self.x } } struct B : A {} B(3).Get()`, "3"},
+		{`struct A { fn Name() { return "A" } } struct B : A { fn Name() { return "B" + super.Name() } } struct C : B {} C().Name()`, "BA"},
+		{`struct A { fn init(x) { self.x = x } } struct B : A { fn init(x, y) { super.init(x) self.y = y } } b = B(1, 2); b.x + b.y`, "3"},
+		{`struct A { n = 1 } struct B : A { m = n + 1 } b = B(); b.n * 10 + b.m`, "12"},
+		{`struct A {} struct B : A {} b = B(); [isinstance(b, A), isinstance(b, B), isinstance(A(), B), isinstance(1, A)]`, "[true, true, false, false]"},
+		{`struct A {} struct B : A {} type(B()) + " " + type(B)`, "B struct"},
+		{`struct A { fn init(x) { self.x = x } } struct B : A {} switch B(5) { case A(x) { r = x } }; r`, "5"},
+		{`struct A {} isinstance(A(), 1)`, "error"},
+		{`struct B : A {}`, "error"},
+		{`struct A : A {}`, "error"},
+		{`struct A { fn F() { return 1 } } struct A : A {}`, "error"},
+		{`struct A {} struct B : A { fn F() { return super.G() } } B().F()`, "error"},
+		{`interface C { fn Compare(other) } struct P : C { fn init(v) { self.v = v } fn Compare(o) { return self.v - o.v } } P(5).Compare(P(3))`, "2"},
+		{`interface C { fn Compare(other) } struct P { fn Compare(o) { return 0 } } struct Q {} [implements(P(), C), implements(Q(), C), implements(1, C)]`, "[true, false, false]"},
+		{`interface C { fn Compare(other) } struct A { fn Compare(o, reverse = false) { return 0 } } struct B : A, C {} implements(B(), C)`, "true"},
+		{`interface S { fn Area(); fn Name() } S.methods()`, `["Area", "Name"]`},
+		{`interface C { fn Compare(other) } struct P : C {}`, "error"},
+		{`interface C { fn Compare(other) } struct P : C { fn Compare() { return 0 } }`, "error"},
+		{`struct A {} struct B {} struct P : A, B {}`, "error"},
+		{`interface C { fn Compare(other) } implements(1, 2)`, "error"},
+		{`struct V { fn init(x) { self.x = x } fn __add__(o) { return V(self.x + o.x) } } (V(1) + V(2)).x`, "3"},
+		{`struct V { fn init(x) { self.x = x } fn __mul__(k) { return V(self.x * k) } fn __rmul__(k) { return V(self.x * k * 10) } } [(V(2) * 3).x, (3 * V(2)).x]`, "[6, 60]"},
+		{`struct V { fn init(x) { self.x = x } fn __lt__(o) { return self.x < o.x } } [V(1) < V(2), V(2) > V(1), V(3) > V(4), V(1) < V(2) < V(3)]`, "[true, true, false, true]"},
+		{`struct V { fn init(x) { self.x = x } fn __eq__(o) { return self.x == o.x } } a = V(1); b = V(1); c = V(2); [a == b, a != b, a != c]`, "[true, false, true]"},
+		{`struct V {} a = V(); b = V(); [a == a, a == b, a != b]`, "[true, false, true]"},
+		{`struct V { fn init(x) { self.x = x } fn __neg__() { return V(-self.x) } } (-V(5)).x`, "-5"},
+		{`struct V { fn init(x) { self.x = x } fn __add__(o) { return V(self.x + o) } } v = V(1); v += 2; v.x`, "3"},
+		{`struct L { fn init() { self.a = [1, 2, 3] } fn __getitem__(i) { return self.a[i] } fn __len__() { return len(self.a) } fn __contains__(x) { return x in self.a } } l = L(); [l[1], len(l), 3 in l, 5 in l]`, "[2, 3, true, false]"},
+		{`struct L { fn __len__() { return "3" } } len(L())`, "error"},
+		{`struct L { fn __len__() { return -1 } } len(L())`, "error"},
+		{`struct L { fn init() { self.h = {} } fn __getitem__(k) { return self.h[k] } fn __setitem__(k, v) { h = self.h; h[k] = v } } l = L(); l["a"] = 1; l["a"] += 10; l["a"]`, "11"},
+		{`struct V {} V() + 1`, "error"},
+		{`struct V {} v = V(); v[0] = 1`, "error"},
+		{`struct P { z = 0 fn init(x, y) { self.x = x self.y = y } fn Show() {} } P(1, "a")`, `P{z: 0, x: 1, y: "a"}`},
+		{`struct P { fn init(x) { self.x = x } fn __str__() { return "<$x>" } } p = P(1); "p = $p"`, "p = <1>"},
+		{`struct P { fn init(x) { self.x = x } fn __str__() { return "<$x>" } } [P(1), P(2)]`, "[<1>, <2>]"},
+		{`struct P { fn init(x) { self.x = x } fn __eq__(o) { return self.x == o.x } fn __hash__() { return self.x } } h = {}; h[P(1)] = "a"; h[P(1)] = "b"; h[P(2)] = "c"; [len(h), h[P(1)]]`, `[2, "b"]`},
+		{`struct P {} p = P(); q = P(); h = {}; h[p] = 1; h[q] = 2; [h[p], h[q], len(h)]`, "[1, 2, 2]"},
+		{`struct P { fn init(x) { self.x = x } fn __eq__(o) { return self.x == o.x } fn __hash__() { return 0 } } h = {}; h[P(1)] = "a"; h[P(2)] = "b"; [len(h), h[P(1)], h[P(2)], P(3) in h]`, `[2, "a", "b", false]`},
+		{`struct P { fn init(x) { self.x = x } fn __eq__(o) { return self.x == o.x } fn __hash__() { return 0 } } h = {P(1): "a", P(2): "b", P(1): "c"}; g = {...h, P(3): "d"}; [len(h), len(g), g[P(1)], g[P(3)]]`, `[2, 3, "c", "d"]`},
+		{`struct P { fn init(x) { self.x = x } fn __eq__(o) { return self.x == o.x } } [P(1) in [P(0), P(1)], P(2) in (P(0), P(1))]`, "[true, false]"},
+		{`struct P { fn __eq__(o) { return true } } h = {}; h[P()] = 1`, "error"},
+		{`struct P { fn __hash__() { return [1] } } h = {}; h[P()] = 1`, "error"},
+		{`struct P { let X = 0 let Y = 0 } P(Y: 2)`, "P{X: 0, Y: 2}"},
+		{`struct P { let _n = 0 fn Inc() { self._n = self._n + 1 return self._n } } p = P(); p.Inc(); p.Inc()`, "2"},
+		{`struct P { let _n = 1 fn Same(o) { return self._n == o._n } } struct Q : P { fn N() { return self._n } } [P().Same(P()), Q().N()]`, "[true, 1]"},
+		{`struct P { const ID = 0 fn init(id) { self.ID = id } } P(3).ID`, "3"},
+		{`struct P { const ID = 0 } P(ID: 4).ID`, "4"},
+		{`struct P { let _n = 0 } P()._n`, "error"},
+		{`struct P { let _n = 0 } p = P(); p._n = 1`, "error"},
+		{`struct P { fn _f() {} } P()._f()`, "error"},
+		{`struct P { const ID = 0 } p = P(); p.ID = 1`, "error"},
+		{`struct P { let X = 0 } P(Z: 1)`, "error"},
+		{`struct P { let _n = 0 } P(_n: 1)`, "error"},
 
 		//import
 		{`import examples.sub_package.calc; println(Add(2,3))`, "nil"},
@@ -231,6 +558,13 @@ func RegisterGoGlobals() (err error) {
 		return
 	}
 
+	err = eval.RegisterGoFunctions("strconv", map[string]interface{}{
+		"ParseUint": strconv.ParseUint,
+	})
+	if err != nil {
+		return
+	}
+
 	err = eval.RegisterGoVars("runtime", map[string]interface{}{
 		"GOOS":   runtime.GOOS,
 		"GOARCH": runtime.GOARCH,
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"magpie/token"
+	"math/big"
 	"strings"
 	"unicode/utf8"
 )
@@ -91,9 +92,10 @@ func (is *ImportStatement) String() string {
 }
 
 //let <identifier1>,<identifier2>,... = <expression1>,<expression2>,...
+//const <identifier1>,<identifier2>,... = <expression1>,<expression2>,...
 type LetStatement struct {
-	Token  token.Token
-	Names  []*Identifier
+	Token  token.Token  //the 'let' or 'const' token
+	Names  []Expression //*Identifier, *ArrayPattern or *HashPattern
 	Values []Expression
 }
 
@@ -207,6 +209,33 @@ func (ts *TailCallStatement) String() string {
 	return out.String()
 }
 
+//defer funcCall(param1, param2, ...)
+//defer obj.method(param1, param2, ...)
+type DeferStatement struct {
+	Token token.Token // the 'defer' token
+	Call  Expression
+}
+
+func (ds *DeferStatement) Pos() token.Position {
+	return ds.Token.Pos
+}
+
+func (ds *DeferStatement) End() token.Position {
+	return ds.Call.End()
+}
+
+func (ds *DeferStatement) statementNode()       {}
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DeferStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ds.TokenLiteral() + " ")
+	out.WriteString(ds.Call.String())
+	out.WriteString(";")
+
+	return out.String()
+}
+
 type BlockStatement struct {
 	Token       token.Token
 	Statements  []Statement
@@ -296,6 +325,33 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// cond ? a : b
+type TernaryExpression struct {
+	Token     token.Token // The '?' token
+	Condition Expression
+	IfTrue    Expression
+	IfFalse   Expression
+}
+
+func (te *TernaryExpression) Pos() token.Position { return te.Condition.Pos() }
+func (te *TernaryExpression) End() token.Position { return te.IfFalse.End() }
+
+func (te *TernaryExpression) expressionNode()      {}
+func (te *TernaryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TernaryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(te.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(te.IfTrue.String())
+	out.WriteString(" : ")
+	out.WriteString(te.IfFalse.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // -2, -3
 type PrefixExpression struct {
 	Token    token.Token
@@ -369,6 +425,57 @@ func (nl *NumberLiteral) expressionNode()      {}
 func (nl *NumberLiteral) TokenLiteral() string { return nl.Token.Literal }
 func (nl *NumberLiteral) String() string       { return nl.Token.Literal }
 
+type IntegerLiteral struct {
+	Token token.Token
+	Value int64
+}
+
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position {
+	length := utf8.RuneCountInString(il.Token.Literal)
+	pos := il.Token.Pos
+	return token.Position{Filename: pos.Filename, Line: pos.Line, Col: pos.Col + length}
+}
+
+func (il *IntegerLiteral) expressionNode()      {}
+func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) String() string       { return il.Token.Literal }
+
+//123n, or an integer literal which overflows int64
+type BigIntLiteral struct {
+	Token token.Token
+	Value *big.Int
+}
+
+func (bl *BigIntLiteral) Pos() token.Position { return bl.Token.Pos }
+func (bl *BigIntLiteral) End() token.Position {
+	length := utf8.RuneCountInString(bl.Token.Literal)
+	pos := bl.Token.Pos
+	return token.Position{Filename: pos.Filename, Line: pos.Line, Col: pos.Col + length}
+}
+
+func (bl *BigIntLiteral) expressionNode()      {}
+func (bl *BigIntLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BigIntLiteral) String() string       { return bl.Token.Literal }
+
+//12.30d, the value is 'Unscaled * 10^(-Scale)', e.g. 12.30d => Unscaled: 1230, Scale: 2
+type DecimalLiteral struct {
+	Token    token.Token
+	Unscaled *big.Int
+	Scale    int32
+}
+
+func (dl *DecimalLiteral) Pos() token.Position { return dl.Token.Pos }
+func (dl *DecimalLiteral) End() token.Position {
+	length := utf8.RuneCountInString(dl.Token.Literal)
+	pos := dl.Token.Pos
+	return token.Position{Filename: pos.Filename, Line: pos.Line, Col: pos.Col + length}
+}
+
+func (dl *DecimalLiteral) expressionNode()      {}
+func (dl *DecimalLiteral) TokenLiteral() string { return dl.Token.Literal }
+func (dl *DecimalLiteral) String() string       { return dl.Token.Literal }
+
 type Identifier struct {
 	Token token.Token
 	Value string
@@ -423,6 +530,16 @@ func (b *BooleanLiteral) String() string       { return b.Token.Literal }
 type StringLiteral struct {
 	Token token.Token
 	Value string
+	Raw   bool          //raw strings are not interpolated
+	Parts []*StringPart //nil if the string has no interpolation
+}
+
+//StringPart is a piece of an interpolated string literal: either plain text,
+//or an expression with an optional format spec, e.g. "${price:.2f}".
+type StringPart struct {
+	Text   string
+	Expr   Expression
+	Format string
 }
 
 func (s *StringLiteral) Pos() token.Position {
@@ -442,7 +559,10 @@ type FunctionLiteral struct {
 	Token      token.Token // The 'fn' token
 	Name       string      // function's name
 	Parameters []*Identifier
+	Values     map[string]Expression //default values of parameters, e.g. fn f(x, y = 10)
+	Patterns   map[string]Expression //destructured parameters, e.g. fn f((x, y)), keyed by the parameter's name
 	Variadic   bool
+	AllArgs    bool //the body uses '$_' to get all the arguments
 	Body       *BlockStatement
 }
 
@@ -461,7 +581,11 @@ func (fl *FunctionLiteral) String() string {
 
 	params := []string{}
 	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+		if v, ok := fl.Values[p.Value]; ok {
+			params = append(params, p.String()+" = "+v.String())
+		} else {
+			params = append(params, p.String())
+		}
 	}
 
 	out.WriteString(fl.TokenLiteral())
@@ -514,6 +638,108 @@ func (a *ArrayLiteral) String() string {
 	return out.String()
 }
 
+//[expr for x in xs if cond]
+type ArrayComprehension struct {
+	Token   token.Token //'['
+	Element Expression
+	Fors    []*ComprehensionFor
+}
+
+func (ac *ArrayComprehension) Pos() token.Position {
+	return ac.Token.Pos
+}
+
+func (ac *ArrayComprehension) End() token.Position {
+	return ac.Fors[len(ac.Fors)-1].End()
+}
+
+func (ac *ArrayComprehension) expressionNode()      {}
+func (ac *ArrayComprehension) TokenLiteral() string { return ac.Token.Literal }
+func (ac *ArrayComprehension) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("[")
+	out.WriteString(ac.Element.String())
+	for _, f := range ac.Fors {
+		out.WriteString(" " + f.String())
+	}
+	out.WriteString("]")
+	return out.String()
+}
+
+//{k: v for k, v in h if cond}
+//@{k: v for k, v in h if cond} (ordered hash)
+type HashComprehension struct {
+	Token     token.Token //'{'
+	Key       Expression
+	Value     Expression
+	Fors      []*ComprehensionFor
+	IsOrdered bool
+}
+
+func (hc *HashComprehension) Pos() token.Position {
+	return hc.Token.Pos
+}
+
+func (hc *HashComprehension) End() token.Position {
+	return hc.Fors[len(hc.Fors)-1].End()
+}
+
+func (hc *HashComprehension) expressionNode()      {}
+func (hc *HashComprehension) TokenLiteral() string { return hc.Token.Literal }
+func (hc *HashComprehension) String() string {
+	var out bytes.Buffer
+
+	if hc.IsOrdered {
+		out.WriteString("@")
+	}
+	out.WriteString("{")
+	out.WriteString(hc.Key.String() + ": " + hc.Value.String())
+	for _, f := range hc.Fors {
+		out.WriteString(" " + f.String())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+//'for x in xs if cond' clause of a comprehension, the clauses after the first one are nested in it.
+type ComprehensionFor struct {
+	Token token.Token  //'for'
+	Key   Expression   //the variable of 'for x in xs', or the key of 'for k, v in h', could be a pattern
+	Value Expression   //nil for 'for x in xs'
+	X     Expression   //value to range over
+	Conds []Expression //'if' conditions after the clause
+}
+
+func (cf *ComprehensionFor) Pos() token.Position {
+	return cf.Token.Pos
+}
+
+func (cf *ComprehensionFor) End() token.Position {
+	if n := len(cf.Conds); n > 0 {
+		return cf.Conds[n-1].End()
+	}
+	return cf.X.End()
+}
+
+func (cf *ComprehensionFor) expressionNode()      {}
+func (cf *ComprehensionFor) TokenLiteral() string { return cf.Token.Literal }
+func (cf *ComprehensionFor) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	out.WriteString(cf.Key.String())
+	if cf.Value != nil {
+		out.WriteString(", " + cf.Value.String())
+	}
+	out.WriteString(" in ")
+	out.WriteString(cf.X.String())
+	for _, cond := range cf.Conds {
+		out.WriteString(" if " + cond.String())
+	}
+	return out.String()
+}
+
 type TupleLiteral struct {
 	Token   token.Token
 	Members []Expression
@@ -549,11 +775,111 @@ func (t *TupleLiteral) String() string {
 	return out.String()
 }
 
+//ArrayPattern destructures an array or a tuple:
+//    let [first, second, ...rest] = arr
+//    fn f((x, y)) {}
+type ArrayPattern struct {
+	Token    token.Token  //'[' or '('
+	Elements []Expression //*Identifier, *ArrayPattern or *HashPattern, any assignable expression in assignments
+	Rest     Expression   //'...rest', nil if not given
+}
+
+func (ap *ArrayPattern) Pos() token.Position {
+	return ap.Token.Pos
+}
+
+func (ap *ArrayPattern) End() token.Position {
+	if ap.Rest != nil {
+		return ap.Rest.End()
+	}
+	aLen := len(ap.Elements)
+	if aLen > 0 {
+		return ap.Elements[aLen-1].End()
+	}
+	return ap.Token.Pos
+}
+
+func (ap *ArrayPattern) expressionNode()      {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range ap.Elements {
+		elements = append(elements, e.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	if ap.Token.Type == token.TOKEN_LPAREN {
+		out.WriteString("(")
+		out.WriteString(strings.Join(elements, ", "))
+		out.WriteString(")")
+	} else {
+		out.WriteString("[")
+		out.WriteString(strings.Join(elements, ", "))
+		out.WriteString("]")
+	}
+	return out.String()
+}
+
+//HashPattern destructures a hash:
+//    let {name, age} = person
+//    let {name: n, "home": {city}, ...others} = person
+type HashPattern struct {
+	Token  token.Token  //'{'
+	Keys   []Expression //an *Identifier key is treated as a string, e.g. {name} or {name: n}
+	Values []Expression //same as the elements of ArrayPattern
+	Rest   Expression   //'...rest', nil if not given
+}
+
+func (hp *HashPattern) Pos() token.Position {
+	return hp.Token.Pos
+}
+
+func (hp *HashPattern) End() token.Position {
+	if hp.Rest != nil {
+		return hp.Rest.End()
+	}
+	vLen := len(hp.Values)
+	if vLen > 0 {
+		return hp.Values[vLen-1].End()
+	}
+	return hp.Token.Pos
+}
+
+func (hp *HashPattern) expressionNode()      {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for i, key := range hp.Keys {
+		if k, ok := key.(*Identifier); ok {
+			if v, ok := hp.Values[i].(*Identifier); ok && v.Value == k.Value { //shorthand, e.g. {name}
+				pairs = append(pairs, k.Value)
+				continue
+			}
+		}
+		pairs = append(pairs, key.String()+": "+hp.Values[i].String())
+	}
+	if hp.Rest != nil {
+		pairs = append(pairs, "..."+hp.Rest.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
 //<Left-Expression>[<Index-Expression>]
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	Optional bool //left?[index]
 }
 
 func (ie *IndexExpression) Pos() token.Position {
@@ -570,6 +896,9 @@ func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("]")
@@ -577,12 +906,55 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+//start:end:step, used as the index of an IndexExpression, e.g. arr[1:3], str[::-1]
+type SliceExpression struct {
+	Token token.Token //the first ':'
+	Start Expression  //nil if omitted
+	Stop  Expression  //nil if omitted
+	Step  Expression  //nil if omitted
+}
+
+func (se *SliceExpression) Pos() token.Position {
+	if se.Start != nil {
+		return se.Start.Pos()
+	}
+	return se.Token.Pos
+}
+
+func (se *SliceExpression) End() token.Position {
+	if se.Step != nil {
+		return se.Step.End()
+	}
+	if se.Stop != nil {
+		return se.Stop.End()
+	}
+	return token.Position{Filename: se.Token.Pos.Filename, Line: se.Token.Pos.Line, Col: se.Token.Pos.Col + 1}
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.Stop != nil {
+		out.WriteString(se.Stop.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
+	return out.String()
+}
+
 type HashLiteral struct {
 	Token       token.Token
 	Pairs       map[Expression]Expression
 	RBraceToken token.Token
 	IsOrdered   bool
-	Order       []Expression //For keeping the order of the hash key
+	Order       []Expression //For keeping the order of the hash key, a *SpreadExpression key means '...hash'
 }
 
 func (h *HashLiteral) Pos() token.Position {
@@ -601,11 +973,19 @@ func (h *HashLiteral) String() string {
 	pairs := []string{}
 	if h.IsOrdered {
 		for _, key := range h.Order {
+			if _, ok := key.(*SpreadExpression); ok {
+				pairs = append(pairs, key.String())
+				continue
+			}
 			value, _ := h.Pairs[key]
 			pairs = append(pairs, key.String()+": "+value.String())
 		}
 	} else {
 		for key, value := range h.Pairs {
+			if _, ok := key.(*SpreadExpression); ok {
+				pairs = append(pairs, key.String())
+				continue
+			}
 			pairs = append(pairs, key.String()+":"+value.String())
 		}
 	}
@@ -617,13 +997,45 @@ func (h *HashLiteral) String() string {
 	return out.String()
 }
 
+//...expr, unboxes 'expr' in array, tuple and hash literals or call arguments, e.g.
+//    [...a, 4, ...b]
+//    {...defaults, "port": 80}
+//    f(1, ...xs, 9)
+type SpreadExpression struct {
+	Token token.Token //the '...' token
+	Value Expression
+}
+
+func (se *SpreadExpression) Pos() token.Position {
+	return se.Token.Pos
+}
+
+func (se *SpreadExpression) End() token.Position {
+	return se.Value.End()
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
 type CallExpression struct {
 	Token     token.Token // The '(' token
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments []Expression
+	NamedArgs []*NamedArgument //keyword arguments, e.g. connect("db", timeout: 5)
 	Variadic  bool
 }
 
+//NamedArgument is a keyword argument of a call, e.g. 'timeout: 5'
+type NamedArgument struct {
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) String() string {
+	return na.Name.String() + ": " + na.Value.String()
+}
+
 func (ce *CallExpression) Pos() token.Position {
 	length := utf8.RuneCountInString(ce.Function.String())
 	return token.Position{Filename: ce.Token.Pos.Filename, Line: ce.Token.Pos.Line, Col: ce.Token.Pos.Col - length}
@@ -646,6 +1058,9 @@ func (ce *CallExpression) String() string {
 	for _, a := range ce.Arguments {
 		args = append(args, a.String())
 	}
+	for _, na := range ce.NamedArgs {
+		args = append(args, na.String())
+	}
 
 	out.WriteString(ce.Function.String())
 	out.WriteString("(")
@@ -658,9 +1073,10 @@ func (ce *CallExpression) String() string {
 }
 
 type MethodCallExpression struct {
-	Token  token.Token
-	Object Expression
-	Call   Expression
+	Token    token.Token
+	Object   Expression
+	Call     Expression
+	Optional bool //obj?.call
 }
 
 func (mc *MethodCallExpression) Pos() token.Position {
@@ -676,6 +1092,9 @@ func (mc *MethodCallExpression) TokenLiteral() string { return mc.Token.Literal
 func (mc *MethodCallExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString(mc.Object.String())
+	if mc.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString(".")
 	out.WriteString(mc.Call.String())
 
@@ -916,10 +1335,11 @@ func (fl *CForLoop) String() string {
 
 //for var in value { block }
 type ForEachArrayLoop struct {
-	Token token.Token
-	Var   string
-	Value Expression //value to range over
-	Block *BlockStatement
+	Token   token.Token
+	Var     string
+	Pattern Expression //for [a, b] in xxx, nil if 'Var' is an identifier
+	Value   Expression //value to range over
+	Block   *BlockStatement
 }
 
 func (fal *ForEachArrayLoop) Pos() token.Position {
@@ -949,11 +1369,13 @@ func (fal *ForEachArrayLoop) String() string {
 
 //for key, value in X { block }
 type ForEachMapLoop struct {
-	Token token.Token
-	Key   string
-	Value string
-	X     Expression //value to range over
-	Block *BlockStatement
+	Token        token.Token
+	Key          string
+	Value        string
+	KeyPattern   Expression //for (k, [a, b]) in xxx, nil if 'Key' is an identifier
+	ValuePattern Expression //nil if 'Value' is an identifier
+	X            Expression //value to range over
+	Block        *BlockStatement
 }
 
 func (fml *ForEachMapLoop) Pos() token.Position {
@@ -1097,7 +1519,8 @@ func (rel *RegExLiteral) String() string {
 
 type StructStatement struct {
 	Token token.Token
-	Name  string //struct's name
+	Name  string        //struct's name
+	Bases []*Identifier //the parent struct and the interfaces, e.g. struct Dog : Animal, Comparable
 
 	Block       *BlockStatement //used in the String() method
 	RBraceToken token.Token     //used in End() method
@@ -1118,6 +1541,13 @@ func (s *StructStatement) String() string {
 
 	out.WriteString(s.Token.Literal + " ")
 	out.WriteString(s.Name)
+	if len(s.Bases) > 0 {
+		bases := []string{}
+		for _, base := range s.Bases {
+			bases = append(bases, base.String())
+		}
+		out.WriteString(" : " + strings.Join(bases, ", "))
+	}
 
 	out.WriteString("{ ")
 	out.WriteString(s.Block.String())
@@ -1126,6 +1556,104 @@ func (s *StructStatement) String() string {
 	return out.String()
 }
 
+//enum Color { Red, Green, Blue = 10 }
+type EnumStatement struct {
+	Token       token.Token
+	Name        string //enum's name
+	Members     []*Identifier
+	Values      []Expression //nil if the member's value is omitted
+	RBraceToken token.Token  //used in End() method
+}
+
+func (e *EnumStatement) Pos() token.Position {
+	return e.Token.Pos
+}
+
+func (e *EnumStatement) End() token.Position {
+	return e.RBraceToken.Pos
+}
+
+func (e *EnumStatement) statementNode()       {}
+func (e *EnumStatement) TokenLiteral() string { return e.Token.Literal }
+func (e *EnumStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(e.Token.Literal + " ")
+	out.WriteString(e.Name)
+
+	members := []string{}
+	for i, member := range e.Members {
+		if e.Values[i] != nil {
+			members = append(members, member.String()+" = "+e.Values[i].String())
+		} else {
+			members = append(members, member.String())
+		}
+	}
+	out.WriteString("{ ")
+	out.WriteString(strings.Join(members, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+//interface Comparable { fn Compare(other) }
+type InterfaceStatement struct {
+	Token       token.Token
+	Name        string //interface's name
+	Methods     []*InterfaceMethod
+	RBraceToken token.Token //used in End() method
+}
+
+func (i *InterfaceStatement) Pos() token.Position {
+	return i.Token.Pos
+}
+
+func (i *InterfaceStatement) End() token.Position {
+	return i.RBraceToken.Pos
+}
+
+func (i *InterfaceStatement) statementNode()       {}
+func (i *InterfaceStatement) TokenLiteral() string { return i.Token.Literal }
+func (i *InterfaceStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(i.Token.Literal + " ")
+	out.WriteString(i.Name)
+
+	methods := []string{}
+	for _, m := range i.Methods {
+		methods = append(methods, m.String())
+	}
+	out.WriteString("{ ")
+	out.WriteString(strings.Join(methods, "; "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+//fn Compare(other), a method without body in the interface
+type InterfaceMethod struct {
+	Token      token.Token // The 'fn' token
+	Name       string
+	Parameters []*Identifier
+	Variadic   bool
+}
+
+func (m *InterfaceMethod) Pos() token.Position {
+	return m.Token.Pos
+}
+
+func (m *InterfaceMethod) String() string {
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+	if m.Variadic {
+		params[len(params)-1] += "..."
+	}
+	return "fn " + m.Name + "(" + strings.Join(params, ", ") + ")"
+}
+
 /*
     switch Expr {
     case expr1, expr2, ... { block1 }
@@ -1169,6 +1697,7 @@ func (se *SwitchExpression) String() string {
 
 /*
    case expr1, expr2, ... { block }
+   case pattern if guard  { block }
    default                { block }
 
 */
@@ -1176,6 +1705,7 @@ type CaseExpression struct {
 	Token       token.Token
 	Default     bool //default case or not
 	Exprs       []Expression
+	Guard       Expression //optional 'if' condition, checked after a pattern matched
 	Block       *BlockStatement
 	RBraceToken token.Token //used in End() method
 }
@@ -1203,6 +1733,10 @@ func (ce *CaseExpression) String() string {
 			exprs = append(exprs, expr.String())
 		}
 		out.WriteString(strings.Join(exprs, ","))
+		if ce.Guard != nil {
+			out.WriteString(" if ")
+			out.WriteString(ce.Guard.String())
+		}
 	}
 	out.WriteString(ce.Block.String())
 	return out.String()
@@ -0,0 +1,380 @@
+package eval
+
+import (
+	"hash/fnv"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	BIGINT_OBJ  = "BIGINT"
+	DECIMAL_OBJ = "DECIMAL"
+)
+
+//The minimal number of digits after the decimal point when dividing two decimals.
+const DECIMAL_DIV_PRECISION = 16
+
+//Arbitrary-precision integer, e.g. 123n
+type BigInt struct {
+	Value *big.Int
+}
+
+func (b *BigInt) Inspect() string {
+	return b.Value.String()
+}
+
+func (b *BigInt) Type() ObjectType { return BIGINT_OBJ }
+
+func (b *BigInt) HashKey() HashKey {
+	//a bigint which fits in int64 should be the same key as the integer.
+	if b.Value.IsInt64() {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(b.Value.Int64())}
+	}
+	h := fnv.New64a()
+	h.Write([]byte(b.Value.String()))
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+func (b *BigInt) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
+	switch method {
+	case "str":
+		return b.str(line, args...)
+	case "int":
+		return b.int(line, args...)
+	case "float":
+		return b.float(line, args...)
+	case "decimal":
+		return b.decimal(line, args...)
+	}
+	return newError(line, ERR_NOMETHOD, method, b.Type())
+}
+
+//b.str(), b.str(16)
+func (b *BigInt) str(line string, args ...Object) Object {
+	argLen := len(args)
+	if argLen > 1 {
+		return newError(line, ERR_ARGUMENT, "0|1", argLen)
+	}
+
+	base := 10
+	if argLen == 1 {
+		var errObj Object
+		if base, errObj = getBase(line, "str", args[0]); errObj != nil {
+			return errObj
+		}
+	}
+	return NewString(b.Value.Text(base))
+}
+
+func (b *BigInt) int(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
+	}
+
+	if !b.Value.IsInt64() {
+		return newError(line, ERR_OVERFLOW, b.Inspect(), INTEGER_OBJ)
+	}
+	return NewInteger(b.Value.Int64())
+}
+
+func (b *BigInt) float(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
+	}
+
+	f, _ := new(big.Float).SetInt(b.Value).Float64()
+	return NewNumber(f)
+}
+
+func (b *BigInt) decimal(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
+	}
+
+	return NewDecimal(b.Value, 0)
+}
+
+func NewBigInt(i *big.Int) *BigInt {
+	return &BigInt{Value: i}
+}
+
+//Decimal number for exact calculations(e.g. money), e.g. 12.30d.
+//The value is 'Unscaled * 10^(-Scale)', so 12.30d is stored as {1230, 2}.
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int32
+}
+
+func (d *Decimal) Inspect() string {
+	str := new(big.Int).Abs(d.Unscaled).String()
+	if d.Scale > 0 {
+		//pad leading zeros, e.g. 5(scale 2) => 005 => 0.05
+		if len(str) <= int(d.Scale) {
+			str = strings.Repeat("0", int(d.Scale)-len(str)+1) + str
+		}
+		pointPos := len(str) - int(d.Scale)
+		str = str[:pointPos] + "." + str[pointPos:]
+	}
+
+	if d.Unscaled.Sign() < 0 {
+		return "-" + str
+	}
+	return str
+}
+
+func (d *Decimal) Type() ObjectType { return DECIMAL_OBJ }
+
+func (d *Decimal) HashKey() HashKey {
+	//12.30d and 12.3d are the same key, and 1.0d is the same key as 1.
+	n := d.normalize()
+	if n.Scale == 0 && n.Unscaled.IsInt64() {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(n.Unscaled.Int64())}
+	}
+	h := fnv.New64a()
+	h.Write([]byte(n.Inspect()))
+	return HashKey{Type: d.Type(), Value: h.Sum64()}
+}
+
+func (d *Decimal) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
+	switch method {
+	case "round":
+		return d.round(line, args...)
+	case "str":
+		return d.str(line, args...)
+	case "int":
+		return d.int(line, args...)
+	case "float":
+		return d.float(line, args...)
+	case "scale":
+		return d.scale(line, args...)
+	}
+	return newError(line, ERR_NOMETHOD, method, d.Type())
+}
+
+func (d *Decimal) round(line string, args ...Object) Object {
+	if len(args) != 1 {
+		return newError(line, ERR_ARGUMENT, "1", len(args))
+	}
+
+	places, ok := toInt64(args[0])
+	if !ok || places < 0 {
+		return newError(line, ERR_PARAMTYPE, "first", "round", "*Integer", args[0].Type())
+	}
+	return d.Round(int32(places))
+}
+
+func (d *Decimal) str(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
+	}
+
+	return NewString(d.Inspect())
+}
+
+func (d *Decimal) int(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
+	}
+
+	i := d.Trunc()
+	if i.IsInt64() {
+		return NewInteger(i.Int64())
+	}
+	return NewBigInt(i)
+}
+
+func (d *Decimal) float(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
+	}
+
+	return NewNumber(d.Float64())
+}
+
+func (d *Decimal) scale(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
+	}
+
+	return NewInteger(int64(d.Scale))
+}
+
+//rescale returns the unscaled value for a larger scale, e.g. 1.2(scale 1) => 120(scale 3)
+func (d *Decimal) rescale(scale int32) *big.Int {
+	if scale <= d.Scale {
+		return d.Unscaled
+	}
+	return new(big.Int).Mul(d.Unscaled, pow10(scale-d.Scale))
+}
+
+//normalize removes the trailing zeros after the decimal point, e.g. 12.30 => 12.3
+func (d *Decimal) normalize() *Decimal {
+	return d.trimZeros(0)
+}
+
+//trimZeros removes trailing zeros after the decimal point, but keeps at least 'minScale' digits.
+func (d *Decimal) trimZeros(minScale int32) *Decimal {
+	unscaled := new(big.Int).Set(d.Unscaled)
+	scale := d.Scale
+	ten := big.NewInt(10)
+	q, r := new(big.Int), new(big.Int)
+	for scale > minScale {
+		q.QuoRem(unscaled, ten, r)
+		if r.Sign() != 0 {
+			break
+		}
+		unscaled.Set(q)
+		scale--
+	}
+	return NewDecimal(unscaled, scale)
+}
+
+func (d *Decimal) Add(other *Decimal) *Decimal {
+	scale := maxScale(d.Scale, other.Scale)
+	return NewDecimal(new(big.Int).Add(d.rescale(scale), other.rescale(scale)), scale)
+}
+
+func (d *Decimal) Sub(other *Decimal) *Decimal {
+	scale := maxScale(d.Scale, other.Scale)
+	return NewDecimal(new(big.Int).Sub(d.rescale(scale), other.rescale(scale)), scale)
+}
+
+func (d *Decimal) Mul(other *Decimal) *Decimal {
+	return NewDecimal(new(big.Int).Mul(d.Unscaled, other.Unscaled), d.Scale+other.Scale)
+}
+
+//Quo returns d / other, rounded half away from zero to at least DECIMAL_DIV_PRECISION digits
+//after the decimal point, then the unneeded trailing zeros are removed, e.g. 10.00d / 4 = 2.50
+func (d *Decimal) Quo(other *Decimal) *Decimal {
+	scale := maxScale(DECIMAL_DIV_PRECISION, maxScale(d.Scale, other.Scale))
+
+	num := new(big.Int).Mul(d.Unscaled, pow10(scale-d.Scale+other.Scale))
+	q, r := new(big.Int).QuoRem(num, other.Unscaled, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(new(big.Int).Abs(other.Unscaled)) >= 0 {
+		if num.Sign() == other.Unscaled.Sign() {
+			q.Add(q, big.NewInt(1))
+		} else {
+			q.Sub(q, big.NewInt(1))
+		}
+	}
+	return NewDecimal(q, scale).trimZeros(maxScale(d.Scale, other.Scale))
+}
+
+//Rem returns the remainder of d / other, which has the same sign as d.
+func (d *Decimal) Rem(other *Decimal) *Decimal {
+	scale := maxScale(d.Scale, other.Scale)
+	return NewDecimal(new(big.Int).Rem(d.rescale(scale), other.rescale(scale)), scale)
+}
+
+//Pow returns d ** exp
+func (d *Decimal) Pow(exp int64) *Decimal {
+	if exp < 0 {
+		return NewDecimal(big.NewInt(1), 0).Quo(d.Pow(-exp))
+	}
+	unscaled := new(big.Int).Exp(d.Unscaled, big.NewInt(exp), nil)
+	return NewDecimal(unscaled, d.Scale*int32(exp))
+}
+
+func (d *Decimal) Cmp(other *Decimal) int {
+	scale := maxScale(d.Scale, other.Scale)
+	return d.rescale(scale).Cmp(other.rescale(scale))
+}
+
+//Round rounds the decimal half away from zero to 'places' digits after the decimal point.
+func (d *Decimal) Round(places int32) *Decimal {
+	if places >= d.Scale {
+		return NewDecimal(d.rescale(places), places)
+	}
+
+	divisor := pow10(d.Scale - places)
+	q, r := new(big.Int).QuoRem(d.Unscaled, divisor, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(divisor) >= 0 {
+		if d.Unscaled.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return NewDecimal(q, places)
+}
+
+//Trunc returns the integral part of the decimal.
+func (d *Decimal) Trunc() *big.Int {
+	return new(big.Int).Quo(d.Unscaled, pow10(d.Scale))
+}
+
+func (d *Decimal) IsInteger() bool {
+	return d.normalize().Scale == 0
+}
+
+func (d *Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.Unscaled, pow10(d.Scale)).Float64()
+	return f
+}
+
+func NewDecimal(unscaled *big.Int, scale int32) *Decimal {
+	return &Decimal{Unscaled: unscaled, Scale: scale}
+}
+
+//NewDecimalFromFloat converts a float to a decimal using the shortest
+//representation of the float, so 0.1 becomes 0.1d, not 0.1000000000000000055511151231257827d.
+func NewDecimalFromFloat(f float64) (*Decimal, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	return parseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+//parseDecimal parses strings like "12.30", "-5", "+0.25"
+func parseDecimal(str string) (*Decimal, bool) {
+	var scale int32
+	if idx := strings.Index(str, "."); idx != -1 {
+		scale = int32(len(str) - idx - 1)
+		str = str[:idx] + str[idx+1:]
+	}
+
+	unscaled, ok := new(big.Int).SetString(str, 10)
+	if !ok {
+		return nil, false
+	}
+	return NewDecimal(unscaled, scale), true
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func maxScale(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+//toBigInt converts an Integer or BigInt to *big.Int
+func toBigInt(obj Object) (*big.Int, bool) {
+	switch o := obj.(type) {
+	case *Integer:
+		return big.NewInt(o.Value), true
+	case *BigInt:
+		return o.Value, true
+	}
+	return nil, false
+}
+
+//toDecimal converts a numeric object to *Decimal
+func toDecimal(obj Object) (*Decimal, bool) {
+	switch o := obj.(type) {
+	case *Integer:
+		return NewDecimal(big.NewInt(o.Value), 0), true
+	case *BigInt:
+		return NewDecimal(o.Value, 0), true
+	case *Number:
+		return NewDecimalFromFloat(o.Value)
+	case *Decimal:
+		return o, true
+	}
+	return nil, false
+}
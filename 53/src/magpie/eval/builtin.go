@@ -2,6 +2,7 @@ package eval
 
 import (
 	"fmt"
+	"math/big"
 	"os"
 	"unicode/utf8"
 )
@@ -102,13 +103,31 @@ func lenBuiltin() *Builtin {
 			switch arg := args[0].(type) {
 			case *String:
 				n := utf8.RuneCountInString(arg.String)
-				return NewNumber(float64(n))
+				return NewInteger(int64(n))
 			case *Array:
-				return NewNumber(float64(len(arg.Members)))
+				return NewInteger(int64(len(arg.Members)))
 			case *Tuple:
-				return NewNumber(float64(len(arg.Members)))
+				return NewInteger(int64(len(arg.Members)))
 			case *Hash:
-				return NewNumber(float64(len(arg.Pairs)))
+				return NewInteger(int64(len(arg.Pairs)))
+			case *Enum:
+				return NewInteger(int64(len(arg.Members)))
+			case *Struct: //len(obj) => obj.__len__()
+				if arg.hasMethod("__len__") {
+					r := arg.callMethod(line, scope, "__len__", nil, nil)
+					if r.Type() == ERROR_OBJ {
+						return r
+					}
+					n, ok := r.(*Integer)
+					if !ok {
+						return newError(line, ERR_LENRESULT, arg.Class.Name, r.Type())
+					}
+					if n.Value < 0 {
+						return newError(line, ERR_LENRESULT, arg.Class.Name, n.Inspect())
+					}
+					return r
+				}
+				return newError(line, "argument to `len` not supported, got %s", args[0].Type())
 			default:
 				return newError(line, "argument to `len` not supported, got %s", args[0].Type())
 			}
@@ -153,13 +172,13 @@ func openBuiltin() *Builtin {
 			}
 
 			if len(args) == 3 {
-				p, ok := args[2].(*Number)
+				p, ok := toInt64(args[2])
 				if !ok {
 					tup.Members[1] = newError(line, ERR_PARAMTYPE, "third", "open", "*Integer", args[2].Type())
 					return tup
 				}
 
-				perm = os.FileMode(int(p.Value))
+				perm = os.FileMode(p)
 			}
 
 			f, err := os.OpenFile(fname.String, flag, perm)
@@ -184,6 +203,11 @@ func init() {
 		"open":        openBuiltin(),
 		"type":        typeBuiltin(),
 		"flushStdout": flushStdoutBuiltin(),
+		"bigint":      bigintBuiltin(),
+		"decimal":     decimalBuiltin(),
+		"freeze":      freezeBuiltin(),
+		"isinstance":  isinstanceBuiltin(),
+		"implements":  implementsBuiltin(),
 	}
 }
 
@@ -194,9 +218,15 @@ func typeBuiltin() *Builtin {
 				return newError(line, ERR_ARGUMENT, 1, len(args))
 			}
 
-			switch args[0].(type) {
+			switch o := args[0].(type) {
 			case *Number:
 				return NewString("number")
+			case *Integer:
+				return NewString("integer")
+			case *BigInt:
+				return NewString("bigint")
+			case *Decimal:
+				return NewString("decimal")
 			case *Nil:
 				return NewString("nil")
 			case *Boolean:
@@ -223,8 +253,18 @@ func typeBuiltin() *Builtin {
 				return NewString("file")
 			case *Os:
 				return NewString("os")
-			case *Struct:
+			case *Struct: //the name of the struct which creates the object
+				return NewString(o.Class.Name)
+			case *StructType:
 				return NewString("struct")
+			case *Super:
+				return NewString("super")
+			case *Interface:
+				return NewString("interface")
+			case *Enum:
+				return NewString("enum")
+			case *EnumMember:
+				return NewString("enummember")
 			case *Throw:
 				return NewString("throw")
 			case *String:
@@ -242,6 +282,95 @@ func typeBuiltin() *Builtin {
 	}
 }
 
+//isinstance(obj, Animal): reports whether 'obj' is created by the struct 'Animal' or the structs derived from it.
+func isinstanceBuiltin() *Builtin {
+	return &Builtin{
+		Fn: func(line string, scope *Scope, args ...Object) Object {
+			if len(args) != 2 {
+				return newError(line, ERR_ARGUMENT, 2, len(args))
+			}
+
+			t, ok := args[1].(*StructType)
+			if !ok {
+				return newError(line, ERR_PARAMTYPE, "second", "isinstance", "*StructType", args[1].Type())
+			}
+
+			if obj, ok := args[0].(*Struct); ok && obj.Class.isSubtypeOf(t) {
+				return TRUE
+			}
+			return FALSE
+		},
+	}
+}
+
+//implements(obj, Comparable): reports whether the struct object 'obj' has all the methods of the interface,
+//the struct need not declare the interface.
+func implementsBuiltin() *Builtin {
+	return &Builtin{
+		Fn: func(line string, scope *Scope, args ...Object) Object {
+			if len(args) != 2 {
+				return newError(line, ERR_ARGUMENT, 2, len(args))
+			}
+
+			iface, ok := args[1].(*Interface)
+			if !ok {
+				return newError(line, ERR_PARAMTYPE, "second", "implements", "*Interface", args[1].Type())
+			}
+
+			if obj, ok := args[0].(*Struct); ok {
+				if ok, _ := obj.Class.implements(line, iface); ok {
+					return TRUE
+				}
+			}
+			return FALSE
+		},
+	}
+}
+
+//freeze(obj): makes arrays, hashes and struct objects immutable(deeply), returns 'obj'.
+func freezeBuiltin() *Builtin {
+	return &Builtin{
+		Fn: func(line string, scope *Scope, args ...Object) Object {
+			if len(args) != 1 {
+				return newError(line, ERR_ARGUMENT, 1, len(args))
+			}
+
+			freeze(args[0])
+			return args[0]
+		},
+	}
+}
+
+func freeze(obj Object) {
+	switch o := obj.(type) {
+	case *Array:
+		if !o.Frozen {
+			o.Frozen = true
+			for _, m := range o.Members {
+				freeze(m)
+			}
+		}
+	case *Tuple: //tuples are immutable, but their members may not be
+		for _, m := range o.Members {
+			freeze(m)
+		}
+	case *Hash:
+		if !o.Frozen {
+			o.Frozen = true
+			for _, pair := range o.Pairs {
+				freeze(pair.Value)
+			}
+		}
+	case *Struct:
+		if !o.Frozen {
+			o.Frozen = true
+			for _, v := range o.Scope.store {
+				freeze(v)
+			}
+		}
+	}
+}
+
 func flushStdoutBuiltin() *Builtin {
 	return &Builtin{
 		Fn: func(line string, scope *Scope, args ...Object) Object {
@@ -254,3 +383,61 @@ func flushStdoutBuiltin() *Builtin {
 		},
 	}
 }
+
+//bigint(10), bigint("123456789012345678901234567890")
+func bigintBuiltin() *Builtin {
+	return &Builtin{
+		Fn: func(line string, scope *Scope, args ...Object) Object {
+			if len(args) != 1 {
+				return newError(line, ERR_ARGUMENT, 1, len(args))
+			}
+
+			switch o := args[0].(type) {
+			case *BigInt:
+				return o
+			case *Integer:
+				return NewBigInt(big.NewInt(o.Value))
+			case *Number:
+				i, _ := new(big.Float).SetFloat64(o.Value).Int(nil)
+				return NewBigInt(i)
+			case *Decimal:
+				return NewBigInt(o.Trunc())
+			case *String:
+				i, ok := new(big.Int).SetString(o.String, 10)
+				if !ok {
+					return newError(line, "can not convert '%s' to bigint", o.String)
+				}
+				return NewBigInt(i)
+			default:
+				return newError(line, ERR_PARAMTYPE, "first", "bigint", "*Integer|*String", args[0].Type())
+			}
+		},
+	}
+}
+
+//decimal(10), decimal(12.5), decimal("12.30")
+func decimalBuiltin() *Builtin {
+	return &Builtin{
+		Fn: func(line string, scope *Scope, args ...Object) Object {
+			if len(args) != 1 {
+				return newError(line, ERR_ARGUMENT, 1, len(args))
+			}
+
+			var d *Decimal
+			var ok bool
+			switch o := args[0].(type) {
+			case *String:
+				d, ok = parseDecimal(o.String)
+			default:
+				if !isNumeric(o) {
+					return newError(line, ERR_PARAMTYPE, "first", "decimal", "*Number|*String", args[0].Type())
+				}
+				d, ok = toDecimal(o)
+			}
+			if !ok {
+				return newError(line, ERR_NOTDECIMAL, args[0].Inspect())
+			}
+			return d
+		},
+	}
+}
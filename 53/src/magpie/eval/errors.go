@@ -6,21 +6,26 @@ import (
 )
 
 var (
-	ERR_ARGUMENT        = "wrong number of arguments. expected=%d, got=%d"
+	ERR_ARGUMENT        = "wrong number of arguments. expected=%v, got=%d"
 	ERR_NOMETHOD        = "undefined method '%s' for object %s"
 	ERR_NOMETHODEX      = "undefined method '%s.%s', Did you mean '%s.%s'?"
 	ERR_INDEX           = "index error: '%d' out of range"
+	ERR_INDEXTYPE       = "index error: index should be an integer, got %s"
 	ERR_KEY             = "key error: type %s is not hashable"
 	ERR_PREFIXOP        = "unsupported operator for prefix expression:'%s' and type: %s"
 	ERR_INFIXOP         = "unsupported operator for infix expression: %s '%s' %s"
 	ERR_POSTFIXOP       = "unsupported operator for postfix expression:'%s' and type: %s"
 	ERR_UNKNOWNIDENT    = "unknown identifier: '%s' is not defined"
 	ERR_DIVIDEBYZERO    = "divide by zero"
+	ERR_OVERFLOW        = "'%s' overflows %s"
+	ERR_RADIX           = "invalid base %d, base must be between 2 and 36"
+	ERR_SHIFTCOUNT      = "negative shift count: %s"
+	ERR_NOTDECIMAL      = "can not convert '%s' to decimal"
 	ERR_NOTFUNCTION     = "expect a function, got %s"
 	ERR_PARAMTYPE       = "%s argument for '%s' should be type %s. got=%s"
 	ERR_NOTITERABLE     = "foreach's operating type must be iterable"
 	ERR_IMPORT          = "import error: %s"
-	ERR_NAMENOTEXPORTED = "cannot refer to unexported name %s.%s"
+	ERR_NAMENOTEXPORTED = "cannot refer to private name %s.%s outside the methods of struct '%s'"
 	ERR_INVALIDARG      = "invalid argument supplied"
 	ERR_NOINDEXABLE     = "index error: type %s is not indexable"
 	ERR_NOTREGEXP       = "right type is not a regexp object, got %s"
@@ -32,6 +37,34 @@ var (
 	ERR_DECORATED_NAME  = "can not find the name of the decorated function"
 	ERR_DECORATOR_FN    = "a decorator must decorate a named function or another decorator"
 	ERR_PIPE            = "pipe operator's right hand side is not a function"
+	ERR_KWARGUNKNOWN    = "unknown keyword argument '%s' for function '%s'"
+	ERR_KWARGDUP        = "function '%s' got multiple values for argument '%s'"
+	ERR_NOKWARGS        = "'%s' does not accept keyword arguments"
+	ERR_DESTRUCTURE     = "cannot destructure %s with pattern '%s'"
+	ERR_PATTERNLEN      = "pattern '%s' expects %s elements, got %d"
+	ERR_PATTERNKEY      = "key %s not found for pattern '%s'"
+	ERR_SLICETYPE       = "type %s does not support slicing"
+	ERR_SLICESTEP       = "slice step cannot be zero"
+	ERR_SLICEASSIGN     = "can only assign an array or a tuple to a slice, got %s"
+	ERR_SLICELEN        = "attempt to assign %d elements to a slice of size %d"
+	ERR_SPREAD          = "type %s can not be unboxed by '...'"
+	ERR_SPREADHASH      = "only a hash can be unboxed by '...' in a hash literal, got %s"
+	ERR_STRUCTPATTERN   = "struct %s accepts at most %d positional patterns, got %d"
+	ERR_DEFER           = "'defer' can only be used inside a function"
+	ERR_CONSTASSIGN     = "cannot assign to constant '%s'"
+	ERR_FROZEN          = "cannot modify a frozen %s"
+	ERR_ENUMVALUE       = "value of enum member '%s' must be an integer, got %s"
+	ERR_ENUMMEMBER      = "'%s' is not a member of enum %s"
+	ERR_PARENTSTRUCT    = "undefined struct or interface '%s' in the declaration of struct '%s'"
+	ERR_SELFPARENT      = "struct '%s' can not inherit from itself"
+	ERR_MULTIPARENT     = "struct '%s' can only have one parent struct, which must be the first one, got '%s'"
+	ERR_NOTIMPLEMENTED  = "struct '%s' does not implement interface '%s': missing method '%s' (declared at %s)"
+	ERR_IMPLSIGNATURE   = "struct '%s' does not implement interface '%s': method '%s' at %s does not match '%s'"
+	ERR_HASHRESULT      = "'__hash__' of struct '%s' should return a hashable value, got %s"
+	ERR_LENRESULT       = "'__len__' of struct '%s' should return a non-negative integer, got %s"
+	ERR_UNHASHABLE      = "struct '%s' defines '__eq__' but not '__hash__', so it can not be used as a hash key"
+	ERR_READONLYFIELD   = "cannot assign to read-only field '%s' of struct '%s'"
+	ERR_UNKNOWNFIELD    = "struct '%s' has no field '%s'"
 )
 
 func newError(line string, format string, args ...interface{}) *Error {
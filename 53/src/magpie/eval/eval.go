@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"magpie/ast"
 	"math"
+	"math/big"
 	"os"
 	"os/exec"
 	"reflect"
@@ -17,6 +18,7 @@ import (
 
 var importMap map[string]*Scope = map[string]*Scope{}
 var ALL_ARGS = "$_"
+var DEFER_RESULT = "$result" //the return value seen by the deferred calls
 
 func panicToError(p interface{}, node ast.Node) *Error {
 	errLine := node.Pos().Sline()
@@ -48,12 +50,22 @@ func Eval(node ast.Node, scope *Scope) (val Object) {
 		return Eval(node.Expression, scope)
 	case *ast.NumberLiteral:
 		return evalNumber(node, scope)
+	case *ast.IntegerLiteral:
+		return evalInteger(node, scope)
+	case *ast.BigIntLiteral:
+		return NewBigInt(node.Value)
+	case *ast.DecimalLiteral:
+		return NewDecimal(node.Unscaled, node.Scale)
 	case *ast.StringLiteral:
 		return evalStringLiteral(node, scope)
 	case *ast.FunctionLiteral:
 		return evalFunctionLiteral(node, scope)
 	case *ast.StructStatement:
 		return evalStructStatement(node, scope)
+	case *ast.EnumStatement:
+		return evalEnumStatement(node, scope)
+	case *ast.InterfaceStatement:
+		return evalInterfaceStatement(node, scope)
 	case *ast.SwitchExpression:
 		return evalSwitchExpression(node, scope)
 	case *ast.TryStmt:
@@ -74,6 +86,9 @@ func Eval(node ast.Node, scope *Scope) (val Object) {
 		if node.Operator == "|>" {
 			return evalPipeInfix(node, scope)
 		}
+		if node.Operator == "&&" || node.Operator == "||" || node.Operator == "??" {
+			return evalLogicalInfix(node, scope)
+		}
 
 		left := Eval(node.Left, scope)
 		if isError(left) {
@@ -84,6 +99,8 @@ func Eval(node ast.Node, scope *Scope) (val Object) {
 			return right
 		}
 		return evalInfixExpression(node, left, right, scope)
+	case *ast.TernaryExpression:
+		return evalTernaryExpression(node, scope)
 	case *ast.PostfixExpression:
 		left := Eval(node.Left, scope)
 		if left.Type() == ERROR_OBJ {
@@ -101,11 +118,21 @@ func Eval(node ast.Node, scope *Scope) (val Object) {
 		}
 
 		return &Array{Members: members}
+	case *ast.ArrayComprehension:
+		return evalArrayComprehension(node, scope)
+	case *ast.HashComprehension:
+		return evalHashComprehension(node, scope)
 	case *ast.IndexExpression:
 		left := Eval(node.Left, scope)
 		if isError(left) {
 			return left
 		}
+		if node.Optional && isNilObject(left) { //e.g. arr?[0]
+			return NIL
+		}
+		if slice, ok := node.Index.(*ast.SliceExpression); ok { //e.g. arr[1:3]
+			return evalSliceExpression(node, left, slice, scope)
+		}
 
 		index := Eval(node.Index, scope)
 		if isError(index) {
@@ -157,6 +184,8 @@ func Eval(node ast.Node, scope *Scope) (val Object) {
 		return evalRegExLiteral(node, scope)
 	case *ast.TailCallStatement:
 		return &TailCall{tail: node}
+	case *ast.DeferStatement:
+		return evalDeferStatement(node, scope)
 	case *ast.DecoratorExpr:
 		return evalDecorator(node, scope)
 	case *ast.CmdExpression:
@@ -249,8 +278,33 @@ func evalNumber(n *ast.NumberLiteral, scope *Scope) Object {
 	return NewNumber(n.Value)
 }
 
+func evalInteger(i *ast.IntegerLiteral, scope *Scope) Object {
+	return NewInteger(i.Value)
+}
+
 func evalStringLiteral(s *ast.StringLiteral, scope *Scope) Object {
-	return NewString(InterpolateString(s.Value, scope))
+	if s.Parts == nil {
+		return NewString(s.Value)
+	}
+
+	var out bytes.Buffer
+	for _, part := range s.Parts {
+		if part.Expr == nil {
+			out.WriteString(part.Text)
+			continue
+		}
+
+		v := Eval(part.Expr, scope)
+		if v.Type() == ERROR_OBJ {
+			return v
+		}
+		if part.Format != "" { //e.g. "${price:.2f}"
+			out.WriteString(fmt.Sprintf("%"+part.Format, &Formatter{Obj: v}))
+		} else {
+			out.WriteString(v.Inspect())
+		}
+	}
+	return NewString(out.String())
 }
 
 func InterpolateString(str string, scope *Scope) string {
@@ -288,13 +342,100 @@ func InterpolateString(str string, scope *Scope) string {
 func evalFunctionLiteral(fl *ast.FunctionLiteral, scope *Scope) Object {
 	fn := &Function{Literal: fl, Scope: scope}
 	if fl.Name != "" {
+		if err := checkAssignable(fl.Pos().Sline(), fl.Name, scope, true); err != nil {
+			return err
+		}
 		scope.Set(fl.Name, fn)
 	}
 	return fn
 }
 
 func evalStructStatement(structStmt *ast.StructStatement, scope *Scope) Object {
-	scope.SetStruct(structStmt) //save to scope
+	t := &StructType{
+		Name:     structStmt.Name,
+		Scope:    scope,
+		Methods:  make(map[string]*ast.FunctionLiteral),
+		cache:    make(map[string]*structMethod),
+		conforms: make(map[*Interface]bool),
+	}
+	var ifaceBases []*ast.Identifier //where the interfaces are declared, for error reporting
+	for i, base := range structStmt.Bases {
+		//the parent must be declared before, so 'struct A : A' is the only way to make a cycle
+		if base.Value == structStmt.Name {
+			return newError(base.Pos().Sline(), ERR_SELFPARENT, structStmt.Name)
+		}
+		if parent, ok := scope.GetStruct(base.Value); ok {
+			if i != 0 {
+				return newError(base.Pos().Sline(), ERR_MULTIPARENT, structStmt.Name, base.Value)
+			}
+			t.Parent = parent
+			continue
+		}
+		if iface, ok := scope.Get(base.Value); ok {
+			if iface, ok := iface.(*Interface); ok {
+				t.Interfaces = append(t.Interfaces, iface)
+				ifaceBases = append(ifaceBases, base)
+				continue
+			}
+		}
+		return newError(base.Pos().Sline(), ERR_PARENTSTRUCT, base.Value, structStmt.Name)
+	}
+
+	//methods are shared by all the objects, the other statements initialize each object's fields
+	for _, stmt := range structStmt.Block.Statements {
+		if es, ok := stmt.(*ast.ExpressionStatement); ok {
+			if fl, ok := es.Expression.(*ast.FunctionLiteral); ok && fl.Name != "" {
+				t.Methods[fl.Name] = fl
+				continue
+			}
+		}
+		t.Fields = append(t.Fields, stmt)
+	}
+
+	//the declared interfaces are checked here, rather than when their methods are called
+	for i, iface := range t.Interfaces {
+		if ok, err := t.implements(ifaceBases[i].Pos().Sline(), iface); !ok {
+			return err
+		}
+	}
+
+	scope.SetStruct(t) //save to scope
+	return NIL
+}
+
+func evalEnumStatement(enumStmt *ast.EnumStatement, scope *Scope) Object {
+	enum := &Enum{Name: enumStmt.Name}
+
+	var value int64
+	for i, member := range enumStmt.Members {
+		if enumStmt.Values[i] != nil { //Blue = 10
+			v := Eval(enumStmt.Values[i], scope)
+			if v.Type() == ERROR_OBJ {
+				return v
+			}
+			if v.Type() != INTEGER_OBJ {
+				return newError(member.Pos().Sline(), ERR_ENUMVALUE, member.Value, v.Type())
+			}
+			value = v.(*Integer).Value
+		}
+		enum.Members = append(enum.Members, &EnumMember{Enum: enum, Name: member.Value, Value: value, Ordinal: i})
+		value++
+	}
+
+	if err := checkAssignable(enumStmt.Pos().Sline(), enum.Name, scope, true); err != nil {
+		return err
+	}
+	scope.SetConst(enum.Name, enum)
+	return NIL
+}
+
+func evalInterfaceStatement(ifaceStmt *ast.InterfaceStatement, scope *Scope) Object {
+	iface := &Interface{Name: ifaceStmt.Name, Methods: ifaceStmt.Methods}
+
+	if err := checkAssignable(ifaceStmt.Pos().Sline(), iface.Name, scope, true); err != nil {
+		return err
+	}
+	scope.SetConst(iface.Name, iface)
 	return NIL
 }
 
@@ -305,6 +446,26 @@ func evalSwitchExpression(switchExpr *ast.SwitchExpression, scope *Scope) Object
 	match := false
 	through := false
 
+	//names captured by a case pattern are only visible inside the case,
+	//'bound' remembers what they shadowed so they can be restored.
+	bound := make(map[string]Object)
+	bind := func(name string, val Object) {
+		if _, ok := bound[name]; !ok {
+			bound[name] = scope.store[name]
+		}
+		scope.Set(name, val)
+	}
+	unbind := func() {
+		for name, old := range bound {
+			if old == nil {
+				scope.Del(name)
+			} else {
+				scope.Set(name, old)
+			}
+			delete(bound, name)
+		}
+	}
+
 loopCases:
 	for _, choice := range switchExpr.Cases { //iterate through all cases
 		if choice.Default {
@@ -315,21 +476,27 @@ loopCases:
 		// only go through the evaluation of the cases when not in fallthrough mode.
 		if !through {
 			for _, expr := range choice.Exprs {
-				out := Eval(expr, scope)
-
-				// literal match?
-				if obj.Type() == out.Type() && (obj.Inspect() == out.Inspect()) {
+				matched, err := matchCase(expr, obj, scope, false, bind)
+				if err != nil {
+					unbind()
+					return err
+				}
+				if matched {
 					match = true
 					break
 				}
+				unbind() //drop the names of a partial match
+			}
 
-				// regexp-match?
-				if out.Type() == REGEX_OBJ {
-					matched := out.(*RegEx).RegExp.MatchString(obj.Inspect())
-					if matched {
-						match = true
-						break
-					}
+			if match && choice.Guard != nil {
+				cond := Eval(choice.Guard, scope)
+				if cond.Type() == ERROR_OBJ {
+					unbind()
+					return cond
+				}
+				if !IsTrue(cond) {
+					match = false
+					unbind()
 				}
 			}
 		}
@@ -337,10 +504,14 @@ loopCases:
 		if match || through {
 			through = false
 			result := evalBlockStatement(choice.Block, scope)
+			unbind()
 			if _, ok := result.(*Fallthrough); ok {
 				through = true
 				continue loopCases
 			}
+			if rt := result.Type(); rt == RETURN_VALUE_OBJ || rt == ERROR_OBJ || rt == THROW_OBJ || rt == TAIL_OBJ {
+				return result
+			}
 			return NIL
 		}
 	}
@@ -353,6 +524,304 @@ loopCases:
 	return NIL
 }
 
+//matchCase reports whether 'val' matches the case expression 'expr'.
+//
+//Array, tuple, hash and struct(e.g. 'Point(x, y)') patterns match the shape of 'val',
+//the names inside them capture the corresponding values, and '_' matches anything.
+//A range('1..10') matches the numbers between its bounds, a regular expression binds
+//its capture groups to '$0', '$1', ... and to the names of the named groups.
+//Anything else is evaluated and compared with 'val'. 'capture' is false for the
+//top level expression, so 'case x' still compares with the value of 'x'.
+func matchCase(expr ast.Expression, val Object, scope *Scope, capture bool, bind func(string, Object)) (bool, Object) {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		if e.Value == "_" {
+			return true, nil
+		}
+		if capture {
+			if err := checkAssignable(e.Pos().Sline(), e.Value, scope, false); err != nil {
+				return false, err
+			}
+			bind(e.Value, val)
+			return true, nil
+		}
+	case *ast.ArrayLiteral:
+		return matchSequence(e.Members, val, scope, bind)
+	case *ast.TupleLiteral:
+		return matchSequence(e.Members, val, scope, bind)
+	case *ast.HashLiteral:
+		return matchHash(e, val, scope, bind)
+	case *ast.CallExpression:
+		if t, ok := scope.GetStruct(e.Function.String()); ok {
+			return matchStruct(e, t, val, scope, bind)
+		}
+	case *ast.InfixExpression:
+		if e.Operator == ".." {
+			return matchRange(e, val, scope)
+		}
+	}
+
+	out := Eval(expr, scope)
+	if out.Type() == ERROR_OBJ {
+		return false, out
+	}
+
+	// literal match?
+	if val.Type() == out.Type() && (val.Inspect() == out.Inspect()) {
+		return true, nil
+	}
+
+	// regexp-match?
+	if re, ok := out.(*RegEx); ok {
+		groups := re.RegExp.FindStringSubmatch(val.Inspect())
+		if groups == nil {
+			return false, nil
+		}
+		names := re.RegExp.SubexpNames()
+		for _, name := range names {
+			if name == "" {
+				continue
+			}
+			if err := checkAssignable(expr.Pos().Sline(), name, scope, false); err != nil {
+				return false, err
+			}
+		}
+		for i, group := range groups {
+			bind(fmt.Sprintf("$%d", i), NewString(group))
+			if names[i] != "" {
+				bind(names[i], NewString(group))
+			}
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
+
+//case [x, y, ...rest]
+//case (x, _)
+func matchSequence(elems []ast.Expression, val Object, scope *Scope, bind func(string, Object)) (bool, Object) {
+	var members []Object
+	switch v := val.(type) {
+	case *Array:
+		members = v.Members
+	case *Tuple:
+		members = v.Members
+	default:
+		return false, nil
+	}
+
+	var rest ast.Expression
+	if n := len(elems); n > 0 {
+		if spread, ok := elems[n-1].(*ast.SpreadExpression); ok {
+			rest = spread.Value
+			elems = elems[:n-1]
+		}
+	}
+
+	n := len(elems)
+	if len(members) < n || (rest == nil && len(members) > n) {
+		return false, nil
+	}
+
+	for i, elem := range elems {
+		if matched, err := matchCase(elem, members[i], scope, true, bind); !matched || err != nil {
+			return false, err
+		}
+	}
+	if rest != nil {
+		arr := &Array{Members: make([]Object, len(members)-n)}
+		copy(arr.Members, members[n:])
+		return matchCase(rest, arr, scope, true, bind)
+	}
+	return true, nil
+}
+
+//case {"type": "add", "value": v, ...rest}
+func matchHash(h *ast.HashLiteral, val Object, scope *Scope, bind func(string, Object)) (bool, Object) {
+	hash, ok := val.(*Hash)
+	if !ok {
+		return false, nil
+	}
+
+	var rest ast.Expression
+	used := make(map[HashKey]bool)
+	for _, k := range h.Order {
+		if spread, ok := k.(*ast.SpreadExpression); ok {
+			rest = spread.Value
+			continue
+		}
+
+		key := Eval(k, scope)
+		if key.Type() == ERROR_OBJ {
+			return false, key
+		}
+		hk, ok, err := hash.lookup(k.Pos().Sline(), key)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+		pair := hash.Pairs[hk]
+		used[hk] = true
+
+		if matched, err := matchCase(h.Pairs[k], pair.Value, scope, true, bind); !matched || err != nil {
+			return false, err
+		}
+	}
+	if rest != nil {
+		r := NewHash()
+		for _, hk := range hash.Order {
+			if !used[hk] {
+				pair := hash.Pairs[hk]
+				r.push(h.Pos().Sline(), pair.Key, pair.Value)
+			}
+		}
+		return matchCase(rest, r, scope, true, bind)
+	}
+	return true, nil
+}
+
+//case Point(x, y), positional patterns match the fields named by the parameters of 'init'.
+//case Point(x: 0, y: y)
+//Objects of the derived structs are matched too.
+func matchStruct(c *ast.CallExpression, t *StructType, val Object, scope *Scope, bind func(string, Object)) (bool, Object) {
+	obj, ok := val.(*Struct)
+	if !ok || !obj.Class.isSubtypeOf(t) {
+		return false, nil
+	}
+
+	var params []*ast.Identifier
+	if m := t.lookup("init"); m != nil {
+		params = m.fn.Parameters
+	}
+	if len(c.Arguments) > len(params) {
+		return false, newError(c.Pos().Sline(), ERR_STRUCTPATTERN, t.Name, len(params), len(c.Arguments))
+	}
+
+	matchField := func(field string, pattern ast.Expression) (bool, Object) {
+		if isPrivateField(field) && !canAccessPrivate(scope, obj) {
+			return false, newError(c.Pos().Sline(), ERR_NAMENOTEXPORTED, t.Name, field, obj.Class.Name)
+		}
+		v, ok := obj.Scope.store[field]
+		if !ok {
+			return false, nil
+		}
+		return matchCase(pattern, v, scope, true, bind)
+	}
+	for i, arg := range c.Arguments {
+		if matched, err := matchField(params[i].Value, arg); !matched || err != nil {
+			return false, err
+		}
+	}
+	for _, arg := range c.NamedArgs {
+		if matched, err := matchField(arg.Name.Value, arg.Value); !matched || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+//case 1..10
+func matchRange(r *ast.InfixExpression, val Object, scope *Scope) (bool, Object) {
+	var bounds [2]float64
+	for i, expr := range []ast.Expression{r.Left, r.Right} {
+		b := Eval(expr, scope)
+		if b.Type() == ERROR_OBJ {
+			return false, b
+		}
+		f, ok := toFloat64(b)
+		if !ok {
+			return false, newError(r.Pos().Sline(), ERR_RANGETYPE, INTEGER_OBJ, b.Type())
+		}
+		bounds[i] = f
+	}
+
+	v, ok := toFloat64(val)
+	if !ok {
+		return false, nil
+	}
+	lo, hi := bounds[0], bounds[1]
+	if lo > hi { //10..1
+		lo, hi = hi, lo
+	}
+	return v >= lo && v <= hi, nil
+}
+
+//evalDeferStatement evaluates the function(or the receiver of the method) and the
+//arguments of the call, and queues the call on the frame of the enclosing function.
+func evalDeferStatement(d *ast.DeferStatement, scope *Scope) Object {
+	frame := scope.frame()
+	if frame == nil {
+		return newError(d.Pos().Sline(), ERR_DEFER)
+	}
+
+	dc := &deferredCall{line: d.Pos().Sline()}
+	switch c := d.Call.(type) {
+	case *ast.CallExpression:
+		dc.fn = Eval(c.Function, scope)
+		if isError(dc.fn) {
+			return dc.fn
+		}
+		args, named, err := evalArguments(c, scope)
+		if err != nil {
+			return err
+		}
+		dc.args, dc.named = args, named
+
+	case *ast.MethodCallExpression:
+		method := c.Call.(*ast.CallExpression)
+		dc.method = method.Function.String()
+		args, named, err := evalArguments(method, scope)
+		if err != nil {
+			return err
+		}
+		dc.args, dc.named = args, named
+
+		if obj, ok := GetGlobalObj(c.Object.String()); ok { //e.g. defer fmt.Println()
+			dc.recv = obj
+			if hash, ok := obj.(*Hash); ok { // It's a GoFuncObject
+				dc.recv = hash.get(dc.line, NewString(dc.method))
+				if isError(dc.recv) {
+					return dc.recv
+				}
+			}
+			break
+		}
+
+		dc.recv = Eval(c.Object, scope)
+		if isError(dc.recv) {
+			return dc.recv
+		}
+		switch recv := dc.recv.(type) {
+		case *Struct:
+			if !unicode.IsUpper(rune(dc.method[0])) && !canAccessPrivate(scope, recv) {
+				return newError(dc.line, ERR_NAMENOTEXPORTED, c.Object.String(), dc.method, recv.Class.Name)
+			}
+		case *Hash: //the function is stored in the hash
+			dc.fn = recv.get(dc.line, NewString(dc.method))
+			if isError(dc.fn) {
+				return dc.fn
+			}
+			dc.recv = nil
+		}
+	}
+
+	if dc.named != nil {
+		if _, ok := dc.fn.(*Function); !ok {
+			switch dc.recv.(type) {
+			case *Struct, *Super:
+			default:
+				return newError(dc.line, ERR_NOKWARGS, d.Call.String())
+			}
+		}
+	}
+
+	frame.deferred = append(frame.deferred, dc)
+	return NIL
+}
+
 func evalThrowStatement(t *ast.ThrowStmt, scope *Scope) Object {
 	throwObj := Eval(t.Expr, scope)
 	if throwObj.Type() == ERROR_OBJ {
@@ -411,18 +880,95 @@ func evalTryStatement(tryStmt *ast.TryStmt, scope *Scope) Object {
 	return rv
 }
 
-func createStructObj(structStmt *ast.StructStatement, scope *Scope) *Struct {
+func createStructObj(t *StructType) Object {
 	structObj := &Struct{
-		Scope: NewScope(scope, nil),
+		Class: t,
+		Scope: NewScope(t.Scope, nil),
+	}
+	structObj.Scope.ordered = true //fields are inspected in declaration order
+
+	//initialize the fields, the parents' first
+	var chain []*StructType
+	for c := t; c != nil; c = c.Parent {
+		chain = append(chain, c)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, stmt := range chain[i].Fields {
+			if r := Eval(stmt, structObj.Scope); r.Type() == ERROR_OBJ {
+				return r
+			}
+		}
+	}
+	return structObj
+}
+
+//newStructObj creates a struct object and calls its 'init' constructor.
+func newStructObj(line string, t *StructType, args []Object, named *namedArgs) Object {
+	obj := createStructObj(t)
+	if obj.Type() == ERROR_OBJ {
+		return obj
 	}
+	structObj := obj.(*Struct)
 
-	Eval(structStmt.Block, structObj.Scope)
-	scope.Set(structStmt.Name, structObj)
+	//the read-only fields can be set until the object is constructed
+	structObj.constructing = true
+	defer func() { structObj.constructing = false }()
 
+	//check if the struct has 'init' function
+	if t.lookup("init") == nil {
+		if len(args) > 0 { //No "init" constructor,but has arguments passed.
+			return newError(line, ERR_NOCONSTRUCTOR, len(args))
+		}
+		//the keyword arguments set the declared fields, e.g. Point(x: 1, y: 2)
+		for i, name := range named.fieldNames() {
+			if _, ok := structObj.Scope.store[name]; !ok {
+				return newError(line, ERR_UNKNOWNFIELD, t.Name, name)
+			}
+			if isPrivateField(name) {
+				return newError(line, ERR_NAMENOTEXPORTED, t.Name, name, t.Name)
+			}
+			structObj.Scope.Set(name, named.values[i])
+		}
+		return structObj
+	}
+	//call `init` constructor, then return the struct object
+	r := structObj.callMethod(line, t.Scope, "init", args, named)
+	if r.Type() == ERROR_OBJ {
+		return r //return error object
+	}
 	return structObj
 }
 
+//isPrivateField reports whether the field can only be accessed in the struct's methods, e.g. '_count'.
+func isPrivateField(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+//canAccessPrivate reports whether the code running in 'scope' is a method of the struct hierarchy
+//of 'obj', where the private fields and methods of 'obj' can be accessed.
+func canAccessPrivate(scope *Scope, obj *Struct) bool {
+	self, ok := scope.Get("self")
+	if !ok {
+		return false
+	}
+	s, ok := self.(*Struct)
+	return ok && (s.Class.isSubtypeOf(obj.Class) || obj.Class.isSubtypeOf(s.Class))
+}
+
+//special methods for the prefix operators
+var prefixMethods = map[string]string{
+	"+": "__pos__",
+	"-": "__neg__",
+	"~": "__invert__",
+}
+
 func evalPrefixExpression(node *ast.PrefixExpression, right Object, scope *Scope) Object {
+	if s, ok := right.(*Struct); ok { //e.g. -v => v.__neg__()
+		if method, ok := prefixMethods[node.Operator]; ok && s.hasMethod(method) {
+			return s.callMethod(node.Pos().Sline(), scope, method, nil, nil)
+		}
+	}
+
 	switch node.Operator {
 	case "+":
 		return evalPlusPrefixOperatorExpression(node, right, scope)
@@ -430,24 +976,48 @@ func evalPrefixExpression(node *ast.PrefixExpression, right Object, scope *Scope
 		return evalMinusPrefixOperatorExpression(node, right, scope)
 	case "!":
 		return evalBangOperatorExpression(node, right, scope)
+	case "~":
+		return evalBitNotOperatorExpression(node, right, scope)
+	default:
+		return newError(node.Pos().Sline(), ERR_PREFIXOP, node.Operator, right.Type())
+	}
+}
+
+// ~x, only integers are supported
+func evalBitNotOperatorExpression(node *ast.PrefixExpression, right Object, scope *Scope) Object {
+	switch r := right.(type) {
+	case *Integer:
+		return NewInteger(^r.Value)
+	case *BigInt:
+		return NewBigInt(new(big.Int).Not(r.Value))
 	default:
 		return newError(node.Pos().Sline(), ERR_PREFIXOP, node.Operator, right.Type())
 	}
 }
 
 func evalPlusPrefixOperatorExpression(node *ast.PrefixExpression, right Object, scope *Scope) Object {
-	if right.Type() != NUMBER_OBJ {
+	if !isNumeric(right) {
 		return newError(node.Pos().Sline(), ERR_PREFIXOP, node.Operator, right.Type())
 	}
 	return right
 }
 
 func evalMinusPrefixOperatorExpression(node *ast.PrefixExpression, right Object, scope *Scope) Object {
-	if right.Type() != NUMBER_OBJ {
+	switch r := right.(type) {
+	case *Integer:
+		if r.Value == math.MinInt64 { //-MinInt64 overflows
+			return NewBigInt(new(big.Int).Neg(big.NewInt(r.Value)))
+		}
+		return NewInteger(-r.Value)
+	case *Number:
+		return NewNumber(-r.Value)
+	case *BigInt:
+		return NewBigInt(new(big.Int).Neg(r.Value))
+	case *Decimal:
+		return NewDecimal(new(big.Int).Neg(r.Unscaled), r.Scale)
+	default:
 		return newError(node.Pos().Sline(), ERR_PREFIXOP, node.Operator, right.Type())
 	}
-	value := right.(*Number).Value
-	return NewNumber(-value)
 }
 
 func evalBangOperatorExpression(node *ast.PrefixExpression, right Object, scope *Scope) Object {
@@ -462,6 +1032,12 @@ func evalInfixExpression(node *ast.InfixExpression, left, right Object, scope *S
 		right = goValueToObject(right.(*GoObject).obj)
 	}
 
+	if left.Type() == STRUCT_OBJ || right.Type() == STRUCT_OBJ {
+		if result, ok := evalStructInfixExpression(node, left, right, scope); ok {
+			return result
+		}
+	}
+
 	operator := node.Operator
 	switch {
 	case operator == "in":
@@ -486,23 +1062,11 @@ func evalInfixExpression(node *ast.InfixExpression, left, right Object, scope *S
 			return TRUE
 		}
 
-	case operator == "&&":
-		leftCond := objectToNativeBoolean(left)
-		if !leftCond {
-			return FALSE
-		}
-
-		rightCond := objectToNativeBoolean(right)
-		return nativeBoolToBooleanObject(leftCond && rightCond)
-	case operator == "||":
-		leftCond := objectToNativeBoolean(left)
-		if leftCond {
-			return TRUE
-		}
-
-		rightCond := objectToNativeBoolean(right)
-		return nativeBoolToBooleanObject(leftCond || rightCond)
-	case left.Type() == NUMBER_OBJ && right.Type() == NUMBER_OBJ:
+	case left.Type() == INTEGER_OBJ && right.Type() == INTEGER_OBJ:
+		return evalIntegerInfixExpression(node, left, right, scope)
+	case isBigNumeric(left) && isNumeric(right), isNumeric(left) && isBigNumeric(right):
+		return evalBigNumberInfixExpression(node, left, right, scope)
+	case isNumeric(left) && isNumeric(right): //float, or integer mixed with float
 		return evalNumberInfixExpression(node, left, right, scope)
 	case left.Type() == STRING_OBJ && right.Type() == STRING_OBJ:
 		return evalStringInfixExpression(node, left, right, scope)
@@ -515,72 +1079,131 @@ func evalInfixExpression(node *ast.InfixExpression, left, right Object, scope *S
 	}
 }
 
-func evalRangeExpression(node *ast.InfixExpression, left, right Object, scope *Scope) Object {
-	arr := &Array{}
-	switch l := left.(type) {
-	case *Number:
-		startVal := int64(l.Value)
+//special methods for the infix operators, the second one is the reflected method
+//which is called on the right operand if the left operand does not have the first one.
+var infixMethods = map[string][2]string{
+	"+":  {"__add__", "__radd__"},
+	"-":  {"__sub__", "__rsub__"},
+	"*":  {"__mul__", "__rmul__"},
+	"/":  {"__div__", "__rdiv__"},
+	"%":  {"__mod__", "__rmod__"},
+	"**": {"__pow__", "__rpow__"},
+	"&":  {"__and__", "__rand__"},
+	"|":  {"__or__", "__ror__"},
+	"^":  {"__xor__", "__rxor__"},
+	"<<": {"__lshift__", "__rlshift__"},
+	">>": {"__rshift__", "__rrshift__"},
+	"<":  {"__lt__", "__gt__"},
+	"<=": {"__le__", "__ge__"},
+	">":  {"__gt__", "__lt__"},
+	">=": {"__ge__", "__le__"},
+	"==": {"__eq__", "__eq__"},
+	"!=": {"__ne__", "__ne__"},
+}
 
-		var endVal int64
-		switch r := right.(type) {
-		case *Number:
-			endVal = int64(r.Value)
-		default:
-			return newError(node.Pos().Sline(), ERR_RANGETYPE, NUMBER_OBJ, right.Type())
-		}
+//evalStructInfixExpression calls the special method of the struct operand, e.g. 'v1 + v2' calls 'v1.__add__(v2)'.
+//The second result is false if neither operand has the method.
+func evalStructInfixExpression(node *ast.InfixExpression, left, right Object, scope *Scope) (Object, bool) {
+	methods, ok := infixMethods[node.Operator]
+	if !ok {
+		return nil, false
+	}
 
-		var j int64
-		if startVal >= endVal {
-			for j = startVal; j >= endVal; j = j - 1 {
-				arr.Members = append(arr.Members, NewNumber(float64(j)))
-			}
-		} else {
-			for j = startVal; j <= endVal; j = j + 1 {
-				arr.Members = append(arr.Members, NewNumber(float64(j)))
-			}
+	line := node.Pos().Sline()
+	var result Object
+	if l, ok := left.(*Struct); ok && l.hasMethod(methods[0]) {
+		result = l.callMethod(line, scope, methods[0], []Object{right}, nil)
+	} else if r, ok := right.(*Struct); ok && r.hasMethod(methods[1]) {
+		result = r.callMethod(line, scope, methods[1], []Object{left}, nil)
+	} else if node.Operator == "!=" { //a != b => !(a == b)
+		eq := &ast.InfixExpression{Token: node.Token, Operator: "=="}
+		if result, ok = evalStructInfixExpression(eq, left, right, scope); !ok {
+			return nil, false
 		}
-	default:
-		return newError(node.Pos().Sline(), ERR_RANGETYPE, NUMBER_OBJ, left.Type())
+		if !isError(result) {
+			result = nativeBoolToBooleanObject(!IsTrue(result))
+		}
+	} else {
+		return nil, false
 	}
 
-	return arr
+	if isError(result) || !node.HasNext {
+		return result, true
+	}
+	if !IsTrue(result) { //a < b < c
+		return FALSE, true
+	}
+	infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
+	r := Eval(node.Next, scope)
+	if isError(r) {
+		return r, true
+	}
+	return evalInfixExpression(infixExpr, right, r, scope), true
 }
 
-func evalInExpression(node *ast.InfixExpression, left, right Object, scope *Scope) Object {
-	switch r := right.(type) {
-	case *String:
-		substr := left.(*String).String
-		idx := strings.Index(r.String, substr)
-		if idx == -1 {
-			return FALSE
-		}
-		return TRUE
-	case *Array:
-		for _, v := range r.Members {
-			r := reflect.DeepEqual(left, v)
-			if r {
-				return TRUE
-			}
+func evalRangeExpression(node *ast.InfixExpression, left, right Object, scope *Scope) Object {
+	arr := &Array{}
+
+	startVal, ok := toInt64(left)
+	if !ok {
+		return newError(node.Pos().Sline(), ERR_RANGETYPE, INTEGER_OBJ, left.Type())
+	}
+
+	endVal, ok := toInt64(right)
+	if !ok {
+		return newError(node.Pos().Sline(), ERR_RANGETYPE, INTEGER_OBJ, right.Type())
+	}
+
+	var j int64
+	if startVal >= endVal {
+		for j = startVal; j >= endVal; j = j - 1 {
+			arr.Members = append(arr.Members, NewInteger(j))
 		}
-		return FALSE
+	} else {
+		for j = startVal; j <= endVal; j = j + 1 {
+			arr.Members = append(arr.Members, NewInteger(j))
+		}
+	}
+
+	return arr
+}
+
+func evalInExpression(node *ast.InfixExpression, left, right Object, scope *Scope) Object {
+	switch r := right.(type) {
+	case *String:
+		substr := left.(*String).String
+		idx := strings.Index(r.String, substr)
+		if idx == -1 {
+			return FALSE
+		}
+		return TRUE
+	case *Array:
+		return evalContains(node, left, r.Members, scope)
 	case *Tuple:
-		for _, v := range r.Members {
-			r := reflect.DeepEqual(left, v)
-			if r {
-				return TRUE
+		return evalContains(node, left, r.Members, scope)
+	case *Hash:
+		_, ok, err := r.lookup(node.Pos().Sline(), left)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(ok)
+	case *Enum: //Color.Red in Color, "Red" in Color
+		switch l := left.(type) {
+		case *EnumMember:
+			return nativeBoolToBooleanObject(l.Enum == r)
+		case *String:
+			for _, m := range r.Members {
+				if m.Name == l.String {
+					return TRUE
+				}
 			}
 		}
 		return FALSE
-	case *Hash:
-		hashable, ok := left.(Hashable)
-		if !ok {
-			return newError(node.Pos().Sline(), ERR_KEY, left.Type())
-		}
-
-		if _, ok = r.Pairs[hashable.HashKey()]; ok {
-			return TRUE
+	case *Struct: //x in obj => obj.__contains__(x)
+		if r.hasMethod("__contains__") {
+			return r.callMethod(node.Pos().Sline(), scope, "__contains__", []Object{left}, nil)
 		}
-		return FALSE
+		return newError(node.Pos().Sline(), ERR_INFIXOP, left.Type(), "in", right.Type())
 	default:
 		return newError(node.Pos().Sline(), ERR_INFIXOP, left.Type(), "in", right.Type())
 	}
@@ -588,6 +1211,28 @@ func evalInExpression(node *ast.InfixExpression, left, right Object, scope *Scop
 	return FALSE
 }
 
+//evalContains reports whether 'members' has 'obj', the struct objects are compared with '__eq__'.
+func evalContains(node *ast.InfixExpression, obj Object, members []Object, scope *Scope) Object {
+	eq := &ast.InfixExpression{Token: node.Token, Operator: "=="}
+	for _, v := range members {
+		if obj.Type() == STRUCT_OBJ || v.Type() == STRUCT_OBJ {
+			if r, ok := evalStructInfixExpression(eq, obj, v, scope); ok {
+				if isError(r) {
+					return r
+				}
+				if IsTrue(r) {
+					return TRUE
+				}
+				continue
+			}
+		}
+		if reflect.DeepEqual(obj, v) {
+			return TRUE
+		}
+	}
+	return FALSE
+}
+
 func evalStringInfixExpression(node *ast.InfixExpression, left, right Object, scope *Scope) Object {
 	leftVal := left.(*String).String
 	rightVal := right.(*String).String
@@ -637,8 +1282,8 @@ func evalNextStringInfix(node *ast.InfixExpression, result *Boolean, right Objec
 }
 
 func evalNumberInfixExpression(node *ast.InfixExpression, left, right Object, scope *Scope) Object {
-	leftVal := left.(*Number).Value
-	rightVal := right.(*Number).Value
+	leftVal, _ := toFloat64(left)
+	rightVal, _ := toFloat64(right)
 
 	switch node.Operator {
 	case "+":
@@ -646,7 +1291,7 @@ func evalNumberInfixExpression(node *ast.InfixExpression, left, right Object, sc
 		if node.HasNext {
 			infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
 			r := Eval(node.Next, scope)
-			return evalNumberInfixExpression(infixExpr, n, r, scope)
+			return evalInfixExpression(infixExpr, n, r, scope)
 		}
 		return n
 	case "-":
@@ -654,7 +1299,7 @@ func evalNumberInfixExpression(node *ast.InfixExpression, left, right Object, sc
 		if node.HasNext {
 			infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
 			r := Eval(node.Next, scope)
-			return evalNumberInfixExpression(infixExpr, n, r, scope)
+			return evalInfixExpression(infixExpr, n, r, scope)
 		}
 		return n
 	case "*":
@@ -662,7 +1307,7 @@ func evalNumberInfixExpression(node *ast.InfixExpression, left, right Object, sc
 		if node.HasNext {
 			infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
 			r := Eval(node.Next, scope)
-			return evalNumberInfixExpression(infixExpr, n, r, scope)
+			return evalInfixExpression(infixExpr, n, r, scope)
 		}
 		return n
 	case "/":
@@ -673,7 +1318,7 @@ func evalNumberInfixExpression(node *ast.InfixExpression, left, right Object, sc
 		if node.HasNext {
 			infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
 			r := Eval(node.Next, scope)
-			return evalNumberInfixExpression(infixExpr, n, r, scope)
+			return evalInfixExpression(infixExpr, n, r, scope)
 		}
 		return n
 	case "%":
@@ -682,7 +1327,7 @@ func evalNumberInfixExpression(node *ast.InfixExpression, left, right Object, sc
 		if node.HasNext {
 			infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
 			r := Eval(node.Next, scope)
-			return evalNumberInfixExpression(infixExpr, n, r, scope)
+			return evalInfixExpression(infixExpr, n, r, scope)
 		}
 		return n
 	case "**":
@@ -690,7 +1335,7 @@ func evalNumberInfixExpression(node *ast.InfixExpression, left, right Object, sc
 		if node.HasNext {
 			infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
 			r := Eval(node.Next, scope)
-			return evalNumberInfixExpression(infixExpr, n, r, scope)
+			return evalInfixExpression(infixExpr, n, r, scope)
 		}
 		return n
 	case "<":
@@ -723,11 +1368,270 @@ func evalNextNumberInfix(node *ast.InfixExpression, result *Boolean, right Objec
 	if result == TRUE {
 		infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
 		r := Eval(node.Next, scope)
-		return evalNumberInfixExpression(infixExpr, right, r, scope)
+		return evalInfixExpression(infixExpr, right, r, scope)
 	}
 	return FALSE
 }
 
+//integer op integer, the result is an integer except for '**' with a negative exponent.
+func evalIntegerInfixExpression(node *ast.InfixExpression, left, right Object, scope *Scope) Object {
+	leftVal := left.(*Integer).Value
+	rightVal := right.(*Integer).Value
+
+	var result Object
+	var val int64
+	var ok = true
+	switch node.Operator {
+	case "+":
+		val, ok = addInt64(leftVal, rightVal)
+		result = NewInteger(val)
+	case "-":
+		val, ok = subInt64(leftVal, rightVal)
+		result = NewInteger(val)
+	case "*":
+		val, ok = mulInt64(leftVal, rightVal)
+		result = NewInteger(val)
+	case "/":
+		if rightVal == 0 {
+			return newError(node.Pos().Sline(), ERR_DIVIDEBYZERO)
+		}
+		ok = !(leftVal == math.MinInt64 && rightVal == -1) //-MinInt64 overflows
+		result = NewInteger(leftVal / rightVal)
+	case "%":
+		if rightVal == 0 {
+			return newError(node.Pos().Sline(), ERR_DIVIDEBYZERO)
+		}
+		result = NewInteger(leftVal % rightVal)
+	case "**":
+		if rightVal < 0 {
+			result = NewNumber(math.Pow(float64(leftVal), float64(rightVal)))
+		} else {
+			val, ok = powInt64(leftVal, rightVal)
+			result = NewInteger(val)
+		}
+	case "&":
+		result = NewInteger(leftVal & rightVal)
+	case "|":
+		result = NewInteger(leftVal | rightVal)
+	case "^":
+		result = NewInteger(leftVal ^ rightVal)
+	case "<<":
+		if rightVal < 0 {
+			return newError(node.Pos().Sline(), ERR_SHIFTCOUNT, right.Inspect())
+		}
+		//overflow if any bit(including the sign bit) is shifted out
+		if rightVal < 63 && (leftVal<<uint(rightVal))>>uint(rightVal) == leftVal {
+			result = NewInteger(leftVal << uint(rightVal))
+		} else {
+			ok = leftVal == 0
+			result = NewInteger(0)
+		}
+	case ">>":
+		if rightVal < 0 {
+			return newError(node.Pos().Sline(), ERR_SHIFTCOUNT, right.Inspect())
+		}
+		result = NewInteger(leftVal >> uint64(rightVal))
+	case "<":
+		return evalNextNumberInfix(node, nativeBoolToBooleanObject(leftVal < rightVal), right, scope)
+	case "<=":
+		return evalNextNumberInfix(node, nativeBoolToBooleanObject(leftVal <= rightVal), right, scope)
+	case ">":
+		return evalNextNumberInfix(node, nativeBoolToBooleanObject(leftVal > rightVal), right, scope)
+	case ">=":
+		return evalNextNumberInfix(node, nativeBoolToBooleanObject(leftVal >= rightVal), right, scope)
+	case "==":
+		return evalNextNumberInfix(node, nativeBoolToBooleanObject(leftVal == rightVal), right, scope)
+	case "!=":
+		return evalNextNumberInfix(node, nativeBoolToBooleanObject(leftVal != rightVal), right, scope)
+	default:
+		return newError(node.Pos().Sline(), ERR_INFIXOP, left.Type(), node.Operator, right.Type())
+	}
+
+	if !ok { //overflow, redo the calculation with bigint
+		return evalBigIntInfixExpression(node, left, right, scope)
+	}
+
+	if node.HasNext {
+		infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
+		r := Eval(node.Next, scope)
+		return evalInfixExpression(infixExpr, result, r, scope)
+	}
+	return result
+}
+
+//bigint or decimal mixed with other numbers. The calculation is done with bigint
+//if both are integers, otherwise with decimal.
+func evalBigNumberInfixExpression(node *ast.InfixExpression, left, right Object, scope *Scope) Object {
+	_, leftIsInt := toBigInt(left)
+	_, rightIsInt := toBigInt(right)
+	if leftIsInt && rightIsInt {
+		return evalBigIntInfixExpression(node, left, right, scope)
+	}
+	return evalDecimalInfixExpression(node, left, right, scope)
+}
+
+func evalBigIntInfixExpression(node *ast.InfixExpression, left, right Object, scope *Scope) Object {
+	leftVal, _ := toBigInt(left)
+	rightVal, _ := toBigInt(right)
+
+	var result Object
+	switch node.Operator {
+	case "+":
+		result = NewBigInt(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		result = NewBigInt(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		result = NewBigInt(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError(node.Pos().Sline(), ERR_DIVIDEBYZERO)
+		}
+		result = NewBigInt(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError(node.Pos().Sline(), ERR_DIVIDEBYZERO)
+		}
+		result = NewBigInt(new(big.Int).Rem(leftVal, rightVal))
+	case "**":
+		if rightVal.Sign() < 0 {
+			return evalDecimalInfixExpression(node, left, right, scope)
+		}
+		result = NewBigInt(new(big.Int).Exp(leftVal, rightVal, nil))
+	case "&":
+		result = NewBigInt(new(big.Int).And(leftVal, rightVal))
+	case "|":
+		result = NewBigInt(new(big.Int).Or(leftVal, rightVal))
+	case "^":
+		result = NewBigInt(new(big.Int).Xor(leftVal, rightVal))
+	case "<<", ">>":
+		if rightVal.Sign() < 0 {
+			return newError(node.Pos().Sline(), ERR_SHIFTCOUNT, right.Inspect())
+		}
+		if !rightVal.IsUint64() || rightVal.Uint64() > math.MaxUint32 {
+			return newError(node.Pos().Sline(), ERR_OVERFLOW, right.Inspect(), "shift count")
+		}
+		if node.Operator == "<<" {
+			result = NewBigInt(new(big.Int).Lsh(leftVal, uint(rightVal.Uint64())))
+		} else {
+			result = NewBigInt(new(big.Int).Rsh(leftVal, uint(rightVal.Uint64())))
+		}
+	case "<", "<=", ">", ">=", "==", "!=":
+		return evalCompareInfix(node, leftVal.Cmp(rightVal), right, scope)
+	default:
+		return newError(node.Pos().Sline(), ERR_INFIXOP, left.Type(), node.Operator, right.Type())
+	}
+
+	if node.HasNext {
+		infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
+		r := Eval(node.Next, scope)
+		return evalInfixExpression(infixExpr, result, r, scope)
+	}
+	return result
+}
+
+func evalDecimalInfixExpression(node *ast.InfixExpression, left, right Object, scope *Scope) Object {
+	leftVal, ok := toDecimal(left)
+	if !ok {
+		return newError(node.Pos().Sline(), ERR_NOTDECIMAL, left.Inspect())
+	}
+	rightVal, ok := toDecimal(right)
+	if !ok {
+		return newError(node.Pos().Sline(), ERR_NOTDECIMAL, right.Inspect())
+	}
+
+	var result Object
+	switch node.Operator {
+	case "+":
+		result = leftVal.Add(rightVal)
+	case "-":
+		result = leftVal.Sub(rightVal)
+	case "*":
+		result = leftVal.Mul(rightVal)
+	case "/":
+		if rightVal.Unscaled.Sign() == 0 {
+			return newError(node.Pos().Sline(), ERR_DIVIDEBYZERO)
+		}
+		result = leftVal.Quo(rightVal)
+	case "%":
+		if rightVal.Unscaled.Sign() == 0 {
+			return newError(node.Pos().Sline(), ERR_DIVIDEBYZERO)
+		}
+		result = leftVal.Rem(rightVal)
+	case "**":
+		if !rightVal.IsInteger() { //e.g. 2.0d ** 0.5, fallback to float
+			result = NewNumber(math.Pow(leftVal.Float64(), rightVal.Float64()))
+			break
+		}
+		exp := rightVal.Trunc()
+		if !exp.IsInt64() {
+			return newError(node.Pos().Sline(), ERR_OVERFLOW, rightVal.Inspect(), INTEGER_OBJ)
+		}
+		if exp.Sign() < 0 && leftVal.Unscaled.Sign() == 0 {
+			return newError(node.Pos().Sline(), ERR_DIVIDEBYZERO)
+		}
+		result = leftVal.Pow(exp.Int64())
+	case "<", "<=", ">", ">=", "==", "!=":
+		return evalCompareInfix(node, leftVal.Cmp(rightVal), right, scope)
+	default:
+		return newError(node.Pos().Sline(), ERR_INFIXOP, left.Type(), node.Operator, right.Type())
+	}
+
+	if node.HasNext {
+		infixExpr := &ast.InfixExpression{Token: node.Token, Operator: node.NextOperator}
+		r := Eval(node.Next, scope)
+		return evalInfixExpression(infixExpr, result, r, scope)
+	}
+	return result
+}
+
+//cmp is the result of comparing left with right: -1 if left < right, 0 if left == right, +1 if left > right
+func evalCompareInfix(node *ast.InfixExpression, cmp int, right Object, scope *Scope) Object {
+	var result bool
+	switch node.Operator {
+	case "<":
+		result = cmp < 0
+	case "<=":
+		result = cmp <= 0
+	case ">":
+		result = cmp > 0
+	case ">=":
+		result = cmp >= 0
+	case "==":
+		result = cmp == 0
+	case "!=":
+		result = cmp != 0
+	}
+	return evalNextNumberInfix(node, nativeBoolToBooleanObject(result), right, scope)
+}
+
+// left && right
+// left || right
+// left ?? right
+//The right operand is only evaluated when the left operand can not decide
+//the result. Like python/javascript, the deciding operand is returned, e.g.
+//    name = opt || "default"
+//Unlike '||', '??' only falls back to the right operand when the left is nil:
+//    count = opt ?? 10   //if opt is 0, count is 0
+func evalLogicalInfix(node *ast.InfixExpression, scope *Scope) Object {
+	left := Eval(node.Left, scope)
+	if isError(left) {
+		return left
+	}
+
+	leftCond := objectToNativeBoolean(left)
+	if node.Operator == "&&" && !leftCond {
+		return left
+	}
+	if node.Operator == "||" && leftCond {
+		return left
+	}
+	if node.Operator == "??" && left != NIL {
+		return left
+	}
+
+	return Eval(node.Right, scope)
+}
+
 func evalPipeInfix(node *ast.InfixExpression, scope *Scope) Object {
 	switch rightFunc := node.Right.(type) {
 	case *ast.MethodCallExpression:
@@ -773,7 +1677,16 @@ func evalPostfixExpression(node *ast.PostfixExpression, left Object, scope *Scop
 }
 
 func evalIncrementPostfixExpression(node *ast.PostfixExpression, left Object, scope *Scope) Object {
+	if err := checkAssignable(node.Pos().Sline(), node.Left.String(), scope, false); err != nil {
+		return err
+	}
+
 	switch left.Type() {
+	case INTEGER_OBJ:
+		leftObj := left.(*Integer)
+		returnVal := NewInteger(leftObj.Value)
+		scope.Set(node.Left.String(), NewInteger(leftObj.Value+1))
+		return returnVal
 	case NUMBER_OBJ:
 		leftObj := left.(*Number)
 		returnVal := NewNumber(leftObj.Value)
@@ -785,7 +1698,16 @@ func evalIncrementPostfixExpression(node *ast.PostfixExpression, left Object, sc
 }
 
 func evalDecrementPostfixExpression(node *ast.PostfixExpression, left Object, scope *Scope) Object {
+	if err := checkAssignable(node.Pos().Sline(), node.Left.String(), scope, false); err != nil {
+		return err
+	}
+
 	switch left.Type() {
+	case INTEGER_OBJ:
+		leftObj := left.(*Integer)
+		returnVal := NewInteger(leftObj.Value)
+		scope.Set(node.Left.String(), NewInteger(leftObj.Value-1))
+		return returnVal
 	case NUMBER_OBJ:
 		leftObj := left.(*Number)
 		returnVal := NewNumber(leftObj.Value)
@@ -818,19 +1740,42 @@ func evalLetStatement(l *ast.LetStatement, scope *Scope) (val Object) {
 		}
 	}
 
+	bind := letBinder(scope)
+	if l.TokenLiteral() == "const" {
+		bind = constBinder(scope)
+	}
+
 	for idx, item := range l.Names {
+		if _, ok := item.(*ast.Identifier); !ok { //let [a, b] = arr
+			val = NIL
+			if idx < valuesLen {
+				val = values[idx]
+			}
+			if val.Type() == ERROR_OBJ {
+				return
+			}
+			if err := bindPattern(item, val, scope, bind); err != nil {
+				return err
+			}
+			continue
+		}
+
 		if idx >= valuesLen { //There are more Names than Values
-			if item.Token.Literal != "_" {
+			if item.TokenLiteral() != "_" {
 				val = NIL
-				scope.Set(item.String(), val)
+				if err := bind(item, val); err != nil {
+					return err
+				}
 			}
 		} else {
-			if item.Token.Literal == "_" { // _: placeholder
+			if item.TokenLiteral() == "_" { // _: placeholder
 				continue
 			}
 			val = values[idx]
 			if val.Type() != ERROR_OBJ {
-				scope.Set(item.String(), val)
+				if err := bind(item, val); err != nil {
+					return err
+				}
 			} else {
 				return
 			}
@@ -840,6 +1785,160 @@ func evalLetStatement(l *ast.LetStatement, scope *Scope) (val Object) {
 	return
 }
 
+//bindPattern destructures 'val' with 'pattern', 'bind' is called with each name of
+//the pattern and the value it gets:
+//    let [first, second, ...rest] = arr
+//    let {name, age: years, ...others} = hash
+//A '_' in the pattern discards its value. The shape of 'val' must match the
+//pattern, e.g. 'let [a, b] = [1, 2, 3]' is an error, use 'let [a, b, ..._]' instead.
+func bindPattern(pattern ast.Expression, val Object, scope *Scope, bind func(ast.Expression, Object) Object) Object {
+	switch pt := pattern.(type) {
+	case *ast.ArrayPattern:
+		var members []Object
+		switch v := val.(type) {
+		case *Array:
+			members = v.Members
+		case *Tuple:
+			members = v.Members
+		default:
+			return newError(pt.Pos().Sline(), ERR_DESTRUCTURE, val.Type(), pt.String())
+		}
+
+		n := len(pt.Elements)
+		if len(members) < n || (pt.Rest == nil && len(members) > n) {
+			expected := fmt.Sprint(n)
+			if pt.Rest != nil {
+				expected = fmt.Sprintf("at least %d", n)
+			}
+			return newError(pt.Pos().Sline(), ERR_PATTERNLEN, pt.String(), expected, len(members))
+		}
+
+		for i, elem := range pt.Elements {
+			if err := bindPattern(elem, members[i], scope, bind); err != nil {
+				return err
+			}
+		}
+		if pt.Rest != nil {
+			rest := make([]Object, len(members)-n)
+			copy(rest, members[n:])
+			return bindPattern(pt.Rest, &Array{Members: rest}, scope, bind)
+		}
+
+	case *ast.HashPattern:
+		hash, ok := val.(*Hash)
+		if !ok {
+			return newError(pt.Pos().Sline(), ERR_DESTRUCTURE, val.Type(), pt.String())
+		}
+
+		used := make(map[HashKey]bool)
+		for i, k := range pt.Keys {
+			var key Object
+			if ident, ok := k.(*ast.Identifier); ok {
+				key = NewString(ident.Value)
+			} else {
+				key = Eval(k, scope)
+				if key.Type() == ERROR_OBJ {
+					return key
+				}
+			}
+
+			hk, ok, err := hash.lookup(k.Pos().Sline(), key)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return newError(k.Pos().Sline(), ERR_PATTERNKEY, key.Inspect(), pt.String())
+			}
+			pair := hash.Pairs[hk]
+			used[hk] = true
+
+			if err := bindPattern(pt.Values[i], pair.Value, scope, bind); err != nil {
+				return err
+			}
+		}
+		if pt.Rest != nil {
+			rest := NewHash()
+			for _, hk := range hash.Order {
+				if !used[hk] {
+					pair := hash.Pairs[hk]
+					rest.push(pt.Pos().Sline(), pair.Key, pair.Value)
+				}
+			}
+			return bindPattern(pt.Rest, rest, scope, bind)
+		}
+
+	default: //a name, or an assignable expression in assignments
+		if pattern.TokenLiteral() == "_" { // _: placeholder
+			return nil
+		}
+		return bind(pattern, val)
+	}
+
+	return nil
+}
+
+//letBinder returns a function for bindPattern() which sets the names in 'scope'.
+func letBinder(scope *Scope) func(ast.Expression, Object) Object {
+	return func(name ast.Expression, val Object) Object {
+		if err := checkAssignable(name.Pos().Sline(), name.String(), scope, true); err != nil {
+			return err
+		}
+		scope.Set(name.String(), val)
+		return nil
+	}
+}
+
+//constBinder returns a function for bindPattern() which sets the names in 'scope' as constants.
+func constBinder(scope *Scope) func(ast.Expression, Object) Object {
+	return func(name ast.Expression, val Object) Object {
+		if err := checkAssignable(name.Pos().Sline(), name.String(), scope, true); err != nil {
+			return err
+		}
+		scope.SetConst(name.String(), val)
+		return nil
+	}
+}
+
+//checkAssignable returns an error if 'name' can not be bound in 'scope': the constants,
+//the imported names and the predefined global objects(e.g. 'stdout'). With 'local' only
+//the constants of 'scope' itself are checked, so 'let' and loop variables can shadow
+//the outer ones.
+func checkAssignable(line string, name string, scope *Scope, local bool) Object {
+	if _, ok := GetGlobalObj(name); ok {
+		return newError(line, ERR_CONSTASSIGN, name)
+	}
+	if (local && scope.readonly[name]) || (!local && scope.IsConst(name)) {
+		return newError(line, ERR_CONSTASSIGN, name)
+	}
+	return nil
+}
+
+//patternNames returns the names a pattern binds, '_' excluded.
+func patternNames(pattern ast.Expression) []string {
+	var names []string
+	switch pt := pattern.(type) {
+	case *ast.ArrayPattern:
+		for _, elem := range pt.Elements {
+			names = append(names, patternNames(elem)...)
+		}
+		if pt.Rest != nil {
+			names = append(names, patternNames(pt.Rest)...)
+		}
+	case *ast.HashPattern:
+		for _, v := range pt.Values {
+			names = append(names, patternNames(v)...)
+		}
+		if pt.Rest != nil {
+			names = append(names, patternNames(pt.Rest)...)
+		}
+	default:
+		if pattern.TokenLiteral() != "_" {
+			names = append(names, pattern.String())
+		}
+	}
+	return names
+}
+
 func evalReturnStatement(r *ast.ReturnStatement, scope *Scope) Object {
 	if r.ReturnValue == nil { //no return value, we default return `NIL` object
 		return &ReturnValue{Value: NIL, Values: []Object{NIL}}
@@ -866,6 +1965,10 @@ func evalIdentifier(node *ast.Identifier, scope *Scope) Object {
 		return val
 	}
 
+	if t, ok := scope.GetStruct(node.Value); ok { //e.g. isinstance(obj, Animal)
+		return t
+	}
+
 	if builtin, ok := builtins[node.Value]; ok {
 		return builtin
 	}
@@ -873,6 +1976,31 @@ func evalIdentifier(node *ast.Identifier, scope *Scope) Object {
 	return newError(node.Pos().Sline(), ERR_UNKNOWNIDENT, node.Value)
 }
 
+//isNilObject reports whether 'obj' is nil, or a go object wrapping a nil value.
+//It's used by the optional chaining operators '?.' and '?['.
+func isNilObject(obj Object) bool {
+	if obj == NIL {
+		return true
+	}
+	if gobj, ok := obj.(*GoObject); ok {
+		return gobj.isNil()
+	}
+	return false
+}
+
+//cond ? a : b, only one of 'a' and 'b' is evaluated
+func evalTernaryExpression(te *ast.TernaryExpression, scope *Scope) Object {
+	condition := Eval(te.Condition, scope)
+	if condition.Type() == ERROR_OBJ {
+		return condition
+	}
+
+	if IsTrue(condition) {
+		return Eval(te.IfTrue, scope)
+	}
+	return Eval(te.IfFalse, scope)
+}
+
 func evalIfExpression(ie *ast.IfExpression, scope *Scope) Object {
 	//eval "if/else-if" part
 	for _, c := range ie.Conditions {
@@ -897,6 +2025,19 @@ func evalIfExpression(ie *ast.IfExpression, scope *Scope) Object {
 func evalExpressions(exps []ast.Expression, scope *Scope) []Object {
 	var result []Object
 	for _, e := range exps {
+		if spread, ok := e.(*ast.SpreadExpression); ok { //e.g. [...a, 4]
+			evaluated := Eval(spread.Value, scope)
+			if isError(evaluated) {
+				return []Object{evaluated}
+			}
+			members, ok := spreadMembers(evaluated)
+			if !ok {
+				return []Object{newError(spread.Pos().Sline(), ERR_SPREAD, evaluated.Type())}
+			}
+			result = append(result, members...)
+			continue
+		}
+
 		evaluated := Eval(e, scope)
 		if isError(evaluated) {
 			return []Object{evaluated}
@@ -908,6 +2049,30 @@ func evalExpressions(exps []ast.Expression, scope *Scope) []Object {
 	return result
 }
 
+//spreadMembers returns the members of 'obj' unboxed by '...', returns false if
+//'obj' can not be unboxed.
+func spreadMembers(obj Object) ([]Object, bool) {
+	switch o := obj.(type) {
+	case *String:
+		var members []Object
+		for _, rune := range o.String {
+			members = append(members, NewString(string(rune)))
+		}
+		return members, true
+	case *Array:
+		return o.Members, true
+	case *Tuple:
+		return o.Members, true
+	case *Enum:
+		return o.members("").(*Array).Members, true
+	case *GoObject:
+		if arr, ok := goValueToObject(o.obj).(*Array); ok {
+			return arr.Members, true
+		}
+	}
+	return nil, false
+}
+
 func evalIndexExpression(node *ast.IndexExpression, left, index Object) Object {
 	switch {
 	case left.Type() == STRING_OBJ:
@@ -918,6 +2083,9 @@ func evalIndexExpression(node *ast.IndexExpression, left, index Object) Object {
 		return evalHashIndexExpression(node.Pos().Sline(), left, index)
 	case left.Type() == TUPLE_OBJ:
 		return evalTupleIndexExpression(node.Pos().Sline(), left, index)
+	case left.Type() == STRUCT_OBJ && left.(*Struct).hasMethod("__getitem__"): //obj[idx] => obj.__getitem__(idx)
+		s := left.(*Struct)
+		return s.callMethod(node.Pos().Sline(), s.Scope, "__getitem__", []Object{index}, nil)
 	default:
 		return newError(node.Pos().Sline(), ERR_NOINDEXABLE, left.Type())
 	}
@@ -926,51 +2094,322 @@ func evalIndexExpression(node *ast.IndexExpression, left, index Object) Object {
 func evalStringIndex(line string, left, index Object) Object {
 	str := left.(*String)
 
-	idx := int64(index.(*Number).Value)
-	max := int64(utf8.RuneCountInString(str.String)) - 1
-	if idx < 0 || idx > max {
+	idx, ok := toInt64(index)
+	if !ok {
+		return newError(line, ERR_INDEXTYPE, index.Type())
+	}
+	i, ok := normalizeIndex(idx, int64(utf8.RuneCountInString(str.String)))
+	if !ok {
 		return newError(line, ERR_INDEX, idx)
 	}
 
-	return NewString(string([]rune(str.String)[idx])) //support utf8,not very efficient
+	return NewString(string([]rune(str.String)[i])) //support utf8,not very efficient
 }
 
 func evalArrayIndexExpression(line string, array, index Object) Object {
 	arrayObject := array.(*Array)
-	idx := int64(index.(*Number).Value)
-	max := int64(len(arrayObject.Members) - 1)
-	if idx < 0 || idx > max {
+	idx, ok := toInt64(index)
+	if !ok {
+		return newError(line, ERR_INDEXTYPE, index.Type())
+	}
+	i, ok := normalizeIndex(idx, int64(len(arrayObject.Members)))
+	if !ok {
 		return newError(line, ERR_INDEX, idx)
 	}
 
-	return arrayObject.Members[idx]
+	return arrayObject.Members[i]
 }
 
 //Almost same as evalArrayIndexExpression
 func evalTupleIndexExpression(line string, tuple, index Object) Object {
 	tupleObject := tuple.(*Tuple)
-	idx := int64(index.(*Number).Value)
-	max := int64(len(tupleObject.Members) - 1)
-	if idx < 0 || idx > max {
+	idx, ok := toInt64(index)
+	if !ok {
+		return newError(line, ERR_INDEXTYPE, index.Type())
+	}
+	i, ok := normalizeIndex(idx, int64(len(tupleObject.Members)))
+	if !ok {
 		return newError(line, ERR_INDEX, idx)
 	}
 
-	return tupleObject.Members[idx]
+	return tupleObject.Members[i]
 }
 
-func evalHashIndexExpression(line string, hash, index Object) Object {
-	hashObject := hash.(*Hash)
-	key, ok := index.(Hashable)
+//normalizeIndex converts a negative index to the index counted from the end,
+//e.g. arr[-1] is the last element. It returns false if the index is out of range.
+func normalizeIndex(idx, length int64) (int64, bool) {
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx >= length {
+		return idx, false
+	}
+	return idx, true
+}
+
+//arr[start:stop:step]
+//tuple[start:stop:step]
+//str[start:stop:step]
+//Like python, negative indices count from the end, out of range indices are clamped
+//and a negative step walks backwards, e.g. str[::-1] is the reversed string.
+func evalSliceExpression(node *ast.IndexExpression, left Object, slice *ast.SliceExpression, scope *Scope) Object {
+	line := node.Pos().Sline()
+
+	var members []Object
+	var runes []rune
+	switch l := left.(type) {
+	case *Array:
+		members = l.Members
+	case *Tuple:
+		members = l.Members
+	case *String:
+		runes = []rune(l.String)
+	default:
+		return newError(line, ERR_SLICETYPE, left.Type())
+	}
+
+	length := len(members)
+	if left.Type() == STRING_OBJ {
+		length = len(runes)
+	}
+	start, stop, step, err := sliceBounds(line, slice, length, scope)
+	if err != nil {
+		return err
+	}
+
+	indices := sliceIndices(start, stop, step)
+	if left.Type() == STRING_OBJ {
+		result := make([]rune, 0, len(indices))
+		for _, i := range indices {
+			result = append(result, runes[i])
+		}
+		return NewString(string(result))
+	}
+
+	result := make([]Object, 0, len(indices))
+	for _, i := range indices {
+		result = append(result, members[i])
+	}
+	if left.Type() == TUPLE_OBJ {
+		return &Tuple{Members: result}
+	}
+	return &Array{Members: result}
+}
+
+//sliceBounds evaluates 'slice' for a sequence of 'length', the omitted parts get their defaults.
+func sliceBounds(line string, slice *ast.SliceExpression, length int, scope *Scope) (start, stop, step int, err Object) {
+	//evaluates a part of the slice, 'ok' is false if the part is omitted or nil
+	part := func(expr ast.Expression) (n int, ok bool, err Object) {
+		if expr == nil {
+			return 0, false, nil
+		}
+		v := Eval(expr, scope)
+		if v.Type() == ERROR_OBJ {
+			return 0, false, v
+		}
+		if v.Type() == NIL_OBJ {
+			return 0, false, nil
+		}
+		i, isInt := toInt64(v)
+		if !isInt {
+			return 0, false, newError(line, ERR_INDEXTYPE, v.Type())
+		}
+		return int(i), true, nil
+	}
+
+	step, ok, err := part(slice.Step)
+	if err != nil {
+		return 0, 0, 0, err
+	}
 	if !ok {
-		return newError(line, ERR_KEY, index.Type())
+		step = 1
+	}
+	if step == 0 {
+		return 0, 0, 0, newError(line, ERR_SLICESTEP)
+	}
+
+	//clamp an index to [0, length] for a positive step, or [-1, length-1] for a negative one
+	clamp := func(i int) int {
+		if i < 0 {
+			i += length
+			if i < 0 {
+				if step < 0 {
+					return -1
+				}
+				return 0
+			}
+		} else if i >= length {
+			if step < 0 {
+				return length - 1
+			}
+			return length
+		}
+		return i
+	}
+
+	start, ok, err = part(slice.Start)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if ok {
+		start = clamp(start)
+	} else if step < 0 {
+		start = length - 1
+	} else {
+		start = 0
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	stop, ok, err = part(slice.Stop)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if ok {
+		stop = clamp(stop)
+	} else if step < 0 {
+		stop = -1
+	} else {
+		stop = length
+	}
+
+	return start, stop, step, nil
+}
+
+//sliceIndices returns the indices from 'start' to 'stop'(exclusive) by 'step'
+func sliceIndices(start, stop, step int) []int {
+	indices := []int{}
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+func evalHashIndexExpression(line string, hash, index Object) Object {
+	hashObject := hash.(*Hash)
+	hk, ok, err := hashObject.lookup(line, index)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		return NIL
 	}
 
-	return pair.Value
+	return hashObject.Pairs[hk].Value
+}
+
+//[x * 2 for x in xs if x > 0]
+//A comprehension is evaluated in a fresh scope, so its variables do not leak.
+func evalArrayComprehension(ac *ast.ArrayComprehension, scope *Scope) Object {
+	arr := &Array{Members: []Object{}}
+	err := evalComprehension(ac.Fors, NewScope(scope, nil), func(s *Scope) Object {
+		v := Eval(ac.Element, s)
+		if v.Type() == ERROR_OBJ {
+			return v
+		}
+		arr.Members = append(arr.Members, v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return arr
+}
+
+//{k: v * 2 for k, v in h if v > 0}
+func evalHashComprehension(hc *ast.HashComprehension, scope *Scope) Object {
+	hash := NewHash()
+	hash.IsOrdered = hc.IsOrdered
+	err := evalComprehension(hc.Fors, NewScope(scope, nil), func(s *Scope) Object {
+		k := Eval(hc.Key, s)
+		if k.Type() == ERROR_OBJ {
+			return k
+		}
+		v := Eval(hc.Value, s)
+		if v.Type() == ERROR_OBJ {
+			return v
+		}
+		if r := hash.push(hc.Key.Pos().Sline(), k, v); r.Type() == ERROR_OBJ {
+			return r
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return hash
+}
+
+//evalComprehension calls 'yield' for each combination of the variables of the 'for'
+//clauses which satisfies their conditions, the later clauses are nested in the former.
+//Like foreach loops, 'for x in xs' ranges over the members of an array, a tuple or a
+//string(or the keys of a hash), 'for i, v in xs' ranges over the indices and members,
+//and 'for k, v in h' ranges over the keys and values of a hash.
+func evalComprehension(fors []*ast.ComprehensionFor, scope *Scope, yield func(*Scope) Object) Object {
+	if len(fors) == 0 {
+		return yield(scope)
+	}
+
+	f := fors[0]
+	x := Eval(f.X, scope)
+	if x.Type() == ERROR_OBJ {
+		return x
+	}
+	if x.Type() == NIL_OBJ {
+		return nil
+	}
+
+	var keys, values []Object
+	if h, ok := x.(*Hash); ok {
+		for _, hk := range h.Order {
+			pair, _ := h.Pairs[hk]
+			keys = append(keys, pair.Key)
+			values = append(values, pair.Value)
+		}
+		if f.Value == nil {
+			values = keys
+		}
+	} else {
+		members, ok := spreadMembers(x)
+		if !ok {
+			return newError(f.Pos().Sline(), ERR_NOTITERABLE)
+		}
+		values = members
+		if f.Value != nil {
+			for i := range members {
+				keys = append(keys, NewInteger(int64(i)))
+			}
+		}
+	}
+
+outer:
+	for i := range values {
+		if f.Value == nil {
+			if err := bindPattern(f.Key, values[i], scope, letBinder(scope)); err != nil {
+				return err
+			}
+		} else {
+			if err := bindPattern(f.Key, keys[i], scope, letBinder(scope)); err != nil {
+				return err
+			}
+			if err := bindPattern(f.Value, values[i], scope, letBinder(scope)); err != nil {
+				return err
+			}
+		}
+
+		for _, cond := range f.Conds {
+			c := Eval(cond, scope)
+			if c.Type() == ERROR_OBJ {
+				return c
+			}
+			if !IsTrue(c) {
+				continue outer
+			}
+		}
+
+		if err := evalComprehension(fors[1:], scope, yield); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func evalHashLiteral(node *ast.HashLiteral, scope *Scope) Object {
@@ -978,21 +2417,38 @@ func evalHashLiteral(node *ast.HashLiteral, scope *Scope) Object {
 	hash.IsOrdered = node.IsOrdered
 
 	for _, key := range node.Order {
+		if spread, ok := key.(*ast.SpreadExpression); ok { //e.g. {...defaults, "port": 80}
+			v := Eval(spread.Value, scope)
+			if v.Type() == ERROR_OBJ {
+				return v
+			}
+			h, ok := v.(*Hash)
+			if !ok {
+				return newError(spread.Pos().Sline(), ERR_SPREADHASH, v.Type())
+			}
+			//the later keys override the earlier ones, but keep their positions
+			for _, hk := range h.Order {
+				pair := h.Pairs[hk]
+				if r := hash.push(node.Pos().Sline(), pair.Key, pair.Value); isError(r) {
+					return r
+				}
+			}
+			continue
+		}
+
 		k := Eval(key, scope)
 		if k.Type() == ERROR_OBJ {
 			return k
 		}
 
-		if _, ok := k.(Hashable); !ok {
-			return newError(node.Pos().Sline(), ERR_KEY, k.Type())
-		}
-
 		value, _ := node.Pairs[key]
 		v := Eval(value, scope)
 		if v.Type() == ERROR_OBJ {
 			return v
 		}
-		hash.push(node.Pos().Sline(), k, v)
+		if r := hash.push(node.Pos().Sline(), k, v); isError(r) {
+			return r
+		}
 	}
 	return hash
 }
@@ -1035,16 +2491,12 @@ func evalMethodCallExpression(call *ast.MethodCallExpression, scope *Scope) Obje
 			}
 		case *ast.CallExpression: //e.g. method call like 'fmt.Printf()'
 			if method, ok := call.Call.(*ast.CallExpression); ok {
-				args := evalExpressions(method.Arguments, scope)
-				if len(args) == 1 && isError(args[0]) {
-					return args[0]
+				args, named, err := evalArguments(method, scope)
+				if err != nil {
+					return err
 				}
-
-				if method.Variadic {
-					args = getVariadicArgs(method, args)
-					if len(args) == 1 && isError(args[0]) {
-						return args[0]
-					}
+				if named != nil {
+					return newError(call.Call.Pos().Sline(), ERR_NOKWARGS, str+"."+o.Function.String())
 				}
 
 				if obj.Type() == HASH_OBJ { // It's a GoFuncObject
@@ -1077,39 +2529,54 @@ func evalMethodCallExpression(call *ast.MethodCallExpression, scope *Scope) Obje
 	if obj.Type() == ERROR_OBJ {
 		return obj
 	}
+	if call.Optional && isNilObject(obj) { //e.g. obj?.field, obj?.method(args), the args are not evaluated
+		return NIL
+	}
 
 	switch m := obj.(type) {
 	case *Struct:
 		switch o := call.Call.(type) {
 		case *ast.Identifier:
-			if i, ok := m.Scope.Get(call.Call.String()); ok {
+			if isPrivateField(o.Value) && !canAccessPrivate(scope, m) {
+				return newError(call.Call.Pos().Sline(), ERR_NAMENOTEXPORTED, str, o.Value, m.Class.Name)
+			}
+			if i, ok := m.get(call.Call.String()); ok {
 				return i
 			}
 		case *ast.CallExpression:
 			funcName := o.Function.String()
-			if !unicode.IsUpper(rune(funcName[0])) && str != "self" {
-				return newError(call.Call.Pos().Sline(), ERR_NAMENOTEXPORTED, call.Object.String(), funcName)
-			}
-			args := evalExpressions(o.Arguments, scope)
-			if len(args) == 1 && isError(args[0]) {
-				return args[0]
+			if !unicode.IsUpper(rune(funcName[0])) && !canAccessPrivate(scope, m) {
+				return newError(call.Call.Pos().Sline(), ERR_NAMENOTEXPORTED, str, funcName, m.Class.Name)
 			}
-
-			if o.Variadic {
-				args = getVariadicArgs(o, args)
-				if len(args) == 1 && isError(args[0]) {
-					return args[0]
-				}
+			args, named, err := evalArguments(o, scope)
+			if err != nil {
+				return err
 			}
 
-			r := obj.CallMethod(call.Call.Pos().Sline(), scope, funcName, args...)
+			r := m.callMethod(call.Call.Pos().Sline(), scope, funcName, args, named)
 			return r
 		case *ast.IndexExpression: //e.g. math.xxx[i] (assume 'math' is a struct)
+			if isPrivateField(o.Left.String()) && !canAccessPrivate(scope, m) {
+				return newError(call.Call.Pos().Sline(), ERR_NAMENOTEXPORTED, str, o.Left.String(), m.Class.Name)
+			}
 			//left := Eval(o.Left, m.Scope)
 			//index := Eval(o.Index, m.Scope)
 			//return evalIndexExpression(o, left, index)
 			return Eval(o, m.Scope)
 		}
+	case *Super: //super.init(args), super.method(args)
+		switch o := call.Call.(type) {
+		case *ast.Identifier:
+			if method := m.Class.lookup(o.Value); method != nil {
+				return &Function{Literal: method.fn, Scope: m.Self.Scope}
+			}
+		case *ast.CallExpression:
+			args, named, err := evalArguments(o, scope)
+			if err != nil {
+				return err
+			}
+			return m.Self.callMethodOf(call.Call.Pos().Sline(), m.Class, o.Function.String(), args, named)
+		}
 	case *Hash:
 		switch o := call.Call.(type) {
 		case *ast.Identifier:
@@ -1125,35 +2592,39 @@ func evalMethodCallExpression(call *ast.MethodCallExpression, scope *Scope) Obje
 	default:
 		if obj.Type() == ARRAY_OBJ {
 			switch call.Call.(type) {
-			case *ast.NumberLiteral:
+			case *ast.NumberLiteral, *ast.IntegerLiteral:
 				index := Eval(call.Call, scope)
 				return evalArrayIndexExpression(call.Call.Pos().Sline(), obj, index)
 			}
 		} else if obj.Type() == TUPLE_OBJ {
 			switch call.Call.(type) {
-			case *ast.NumberLiteral:
+			case *ast.NumberLiteral, *ast.IntegerLiteral:
 				index := Eval(call.Call, scope)
 				return evalTupleIndexExpression(call.Call.Pos().Sline(), m, index)
 			}
 		} else if obj.Type() == STRING_OBJ {
 			switch call.Call.(type) {
-			case *ast.NumberLiteral:
+			case *ast.NumberLiteral, *ast.IntegerLiteral:
 				index := Eval(call.Call, scope)
 				return evalStringIndex(call.Call.Pos().Sline(), m, index)
 			}
+		} else if obj.Type() == ENUM_OBJ {
+			if member, ok := call.Call.(*ast.Identifier); ok { //e.g. Color.Red
+				return m.(*Enum).member(call.Call.Pos().Sline(), member.Value)
+			}
+		} else if obj.Type() == ENUM_MEMBER_OBJ {
+			if field, ok := call.Call.(*ast.Identifier); ok { //e.g. Color.Red.name
+				return m.(*EnumMember).field(call.Call.Pos().Sline(), field.Value)
+			}
 		}
 
 		if method, ok := call.Call.(*ast.CallExpression); ok {
-			args := evalExpressions(method.Arguments, scope)
-			if len(args) == 1 && isError(args[0]) {
-				return args[0]
+			args, named, err := evalArguments(method, scope)
+			if err != nil {
+				return err
 			}
-
-			if method.Variadic {
-				args = getVariadicArgs(method, args)
-				if len(args) == 1 && isError(args[0]) {
-					return args[0]
-				}
+			if named != nil {
+				return newError(call.Call.Pos().Sline(), ERR_NOKWARGS, call.Object.String()+"."+method.Function.String())
 			}
 
 			return obj.CallMethod(call.Call.Pos().Sline(), scope, method.Function.String(), args...)
@@ -1197,8 +2668,10 @@ func evalMultiAssignStatement(ma *ast.MultiAssignStatement, scope *Scope) Object
 			continue
 		}
 
-		a := &ast.AssignExpression{Token: ma.Token, Name: name}
-		_evalAssignExpression(a, values[idx], scope)
+		a := &ast.AssignExpression{Token: ma.Token, Name: name} //also handles 'a, [b, c] = 1, [2, 3]'
+		if r := _evalAssignExpression(a, values[idx], scope); r.Type() == ERROR_OBJ {
+			return r
+		}
 	}
 
 	return NIL
@@ -1214,6 +2687,21 @@ func evalAssignExpression(a *ast.AssignExpression, scope *Scope) Object {
 }
 
 func _evalAssignExpression(a *ast.AssignExpression, val Object, scope *Scope) Object {
+	switch a.Name.(type) {
+	case *ast.ArrayPattern, *ast.HashPattern: //[a, b] = [b, a], {name} = h
+		err := bindPattern(a.Name, val, scope, func(name ast.Expression, v Object) Object {
+			r := _evalAssignExpression(&ast.AssignExpression{Token: a.Token, Name: name}, v, scope)
+			if r.Type() == ERROR_OBJ {
+				return r
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return val
+	}
+
 	if strings.Contains(a.Name.String(), ".") {
 		switch o := a.Name.(type) {
 		case *ast.MethodCallExpression: //structObj.x = 10
@@ -1223,8 +2711,17 @@ func _evalAssignExpression(a *ast.AssignExpression, val Object, scope *Scope) Ob
 			}
 			switch m := obj.(type) {
 			case *Struct:
+				if m.Frozen {
+					return newError(a.Pos().Sline(), ERR_FROZEN, m.Type())
+				}
 				switch c := o.Call.(type) {
 				case *ast.Identifier:
+					if isPrivateField(c.Value) && !canAccessPrivate(scope, m) {
+						return newError(a.Pos().Sline(), ERR_NAMENOTEXPORTED, o.Object.String(), c.Value, m.Class.Name)
+					}
+					if m.Scope.readonly[c.Value] && !m.constructing {
+						return newError(a.Pos().Sline(), ERR_READONLYFIELD, c.Value, m.Class.Name)
+					}
 					m.Scope.Set(c.Value, val)
 					return val
 				case *ast.IndexExpression: //structObj.xxx[idx]
@@ -1232,6 +2729,9 @@ func _evalAssignExpression(a *ast.AssignExpression, val Object, scope *Scope) Ob
 					var ok bool
 
 					name := c.Left.(*ast.Identifier).Value
+					if isPrivateField(name) && !canAccessPrivate(scope, m) {
+						return newError(a.Pos().Sline(), ERR_NAMENOTEXPORTED, o.Object.String(), name, m.Class.Name)
+					}
 					if left, ok = m.Scope.Get(name); !ok {
 						return newError(a.Pos().Sline(), ERR_UNKNOWNIDENT, name)
 					}
@@ -1250,19 +2750,25 @@ func _evalAssignExpression(a *ast.AssignExpression, val Object, scope *Scope) Ob
 					//error
 				}
 			case *Hash: //h.key = xxx
+				if m.Frozen {
+					return newError(a.Pos().Sline(), ERR_FROZEN, m.Type())
+				}
 				key := NewString(o.Call.String()) //we treat 'key' as string
 				m.push(a.Pos().Sline(), key, val)
 				return NIL
 			case *Array: //a.1 = xxx
+				if m.Frozen {
+					return newError(a.Pos().Sline(), ERR_FROZEN, m.Type())
+				}
 				switch o.Call.(type) {
-				case *ast.NumberLiteral:
+				case *ast.NumberLiteral, *ast.IntegerLiteral:
 					index := Eval(o.Call, scope)
 					m.set(o.Call.Pos().Sline(), index, val)
 				}
 				return NIL
 			case *String: //s.1 = xxx
 				switch o.Call.(type) {
-				case *ast.NumberLiteral:
+				case *ast.NumberLiteral, *ast.IntegerLiteral:
 					index := Eval(o.Call, scope)
 					m.set(o.Call.Pos().Sline(), index, val)
 				}
@@ -1276,6 +2782,9 @@ func _evalAssignExpression(a *ast.AssignExpression, val Object, scope *Scope) Ob
 	//a = 10
 	case *ast.Identifier:
 		name = nodeType.Value
+		if err := checkAssignable(a.Pos().Sline(), name, scope, false); err != nil {
+			return err
+		}
 
 	//arr[idx] = "xxx", here `a.Name` = arr[idx]
 	case *ast.IndexExpression:
@@ -1303,7 +2812,7 @@ func _evalAssignExpression(a *ast.AssignExpression, val Object, scope *Scope) Ob
 	}
 
 	switch left.Type() {
-	case NUMBER_OBJ:
+	case NUMBER_OBJ, INTEGER_OBJ, BIGINT_OBJ, DECIMAL_OBJ:
 		return evalNumAssignExpression(a, name, left, scope, val)
 	case STRING_OBJ:
 		return evalStrAssignExpression(a, name, left, scope, val)
@@ -1313,34 +2822,73 @@ func _evalAssignExpression(a *ast.AssignExpression, val Object, scope *Scope) Ob
 		return evalTupleAssignExpression(a, name, left, scope, val)
 	case HASH_OBJ:
 		return evalHashAssignExpression(a, name, left, scope, val)
+	case STRUCT_OBJ:
+		return evalStructAssignExpression(a, name, left, scope, val)
 	}
 
 	return newError(a.Pos().Sline(), ERR_INFIXOP, left.Type(), a.Token.Literal, val.Type())
 }
 
+//obj[idx] = xxx => obj.__setitem__(idx, xxx)
+//obj[idx] += xxx => obj.__setitem__(idx, obj.__getitem__(idx) + xxx)
+//obj += xxx => obj = obj.__add__(xxx)
+func evalStructAssignExpression(a *ast.AssignExpression, name string, left Object, scope *Scope, val Object) Object {
+	s := left.(*Struct)
+	line := a.Pos().Sline()
+	operator := strings.TrimSuffix(a.Token.Literal, "=") //'+=' -> '+', '-=' -> '-', etc.
+
+	switch nodeType := a.Name.(type) {
+	case *ast.Identifier:
+		if a.Token.Literal == "=" {
+			break
+		}
+		infixExpr := &ast.InfixExpression{Token: a.Token, Operator: operator}
+		ret := evalInfixExpression(infixExpr, s, val, scope)
+		if isError(ret) {
+			return ret
+		}
+		scope.Set(name, ret)
+		return ret
+	case *ast.IndexExpression:
+		if !s.hasMethod("__setitem__") {
+			return newError(line, ERR_NOMETHOD, "__setitem__", s.Type())
+		}
+		index := Eval(nodeType.Index, scope)
+		if isError(index) {
+			return index
+		}
+		if a.Token.Literal != "=" {
+			old := evalIndexExpression(nodeType, s, index)
+			if isError(old) {
+				return old
+			}
+			infixExpr := &ast.InfixExpression{Token: a.Token, Operator: operator}
+			val = evalInfixExpression(infixExpr, old, val, scope)
+			if isError(val) {
+				return val
+			}
+		}
+		if r := s.callMethod(line, scope, "__setitem__", []Object{index, val}, nil); isError(r) {
+			return r
+		}
+		return val
+	}
+
+	return newError(line, ERR_INFIXOP, left.Type(), a.Token.Literal, val.Type())
+}
+
 // num += num
 // num -= num
 // etc...
 func evalNumAssignExpression(a *ast.AssignExpression, name string, left Object, scope *Scope, val Object) (ret Object) {
-	if left.Type() == NUMBER_OBJ && val.Type() == NUMBER_OBJ {
-		leftVal := left.(*Number).Value
-		rightVal := val.(*Number).Value
-
-		var result float64
-		switch a.Token.Literal {
-		case "+=":
-			result = leftVal + rightVal
-		case "-=":
-			result = leftVal - rightVal
-		case "*=":
-			result = leftVal * rightVal
-		case "/=":
-			result = leftVal / rightVal
-		case "%=":
-			result = math.Mod(leftVal, rightVal)
-		}
-
-		ret = NewNumber(result)
+	if a.Token.Literal != "=" && isNumeric(val) {
+		//'+=' -> '+', '-=' -> '-', etc.
+		operator := strings.TrimSuffix(a.Token.Literal, "=")
+		infixExpr := &ast.InfixExpression{Token: a.Token, Operator: operator}
+		ret = evalInfixExpression(infixExpr, left, val, scope)
+		if isError(ret) {
+			return
+		}
 		scope.Set(name, ret)
 		return
 	}
@@ -1356,17 +2904,26 @@ func evalStrAssignExpression(a *ast.AssignExpression, name string, left Object,
 	case "=":
 		switch nodeType := a.Name.(type) {
 		case *ast.IndexExpression: //str[idx] = xxx
+			if slice, ok := nodeType.Index.(*ast.SliceExpression); ok { //str[start:stop] = xxx
+				return evalStrSliceAssignExpression(a, slice, name, leftVal, scope, val)
+			}
+
 			index := Eval(nodeType.Index, scope)
 			if index == NIL {
 				ret = NIL
 				return
 			}
 
-			idx := int64(index.(*Number).Value)
+			idx, ok := toInt64(index)
+			if !ok {
+				return newError(a.Pos().Sline(), ERR_INDEXTYPE, index.Type())
+			}
 
-			if idx < 0 || idx >= int64(len(leftVal)) {
+			i, ok := normalizeIndex(idx, int64(len(leftVal)))
+			if !ok {
 				return newError(a.Pos().Sline(), ERR_INDEX, idx)
 			}
+			idx = i
 
 			ret = NewString(leftVal[:idx] + val.Inspect() + leftVal[idx+1:])
 			scope.Set(name, ret)
@@ -1385,10 +2942,51 @@ func evalStrAssignExpression(a *ast.AssignExpression, name string, left Object,
 	return newError(a.Pos().Sline(), ERR_INFIXOP, left.Type(), a.Token.Literal, val.Type())
 }
 
+//str[start:stop] = xxx, the characters of the slice are replaced with the string 'val'.
+//With a step other than 1, 'val' must have as many characters as the slice.
+func evalStrSliceAssignExpression(a *ast.AssignExpression, slice *ast.SliceExpression, name string, leftVal string, scope *Scope, val Object) Object {
+	line := a.Pos().Sline()
+
+	str, ok := val.(*String)
+	if !ok {
+		return newError(line, ERR_INFIXOP, STRING_OBJ, a.Token.Literal, val.Type())
+	}
+
+	runes := []rune(leftVal)
+	vals := []rune(str.String)
+	start, stop, step, err := sliceBounds(line, slice, len(runes), scope)
+	if err != nil {
+		return err
+	}
+
+	var ret Object
+	if step == 1 {
+		if stop < start {
+			stop = start
+		}
+		ret = NewString(string(runes[:start]) + string(vals) + string(runes[stop:]))
+	} else {
+		indices := sliceIndices(start, stop, step)
+		if len(indices) != len(vals) {
+			return newError(line, ERR_SLICELEN, len(vals), len(indices))
+		}
+		for i, idx := range indices {
+			runes[idx] = vals[i]
+		}
+		ret = NewString(string(runes))
+	}
+	scope.Set(name, ret)
+	return ret
+}
+
 //array[idx] = item
 //array += item (push item to end of array)
 //array[idx] += item
 func evalArrayAssignExpression(a *ast.AssignExpression, name string, left Object, scope *Scope, val Object) (ret Object) {
+	if left.(*Array).Frozen {
+		return newError(a.Pos().Sline(), ERR_FROZEN, left.Type())
+	}
+
 	leftVals := left.(*Array).Members
 	switch a.Token.Literal {
 	case "+=":
@@ -1403,15 +3001,26 @@ func evalArrayAssignExpression(a *ast.AssignExpression, name string, left Object
 	case "=":
 		switch nodeType := a.Name.(type) {
 		case *ast.IndexExpression: //arr[idx] = xxx
+			if slice, ok := nodeType.Index.(*ast.SliceExpression); ok { //arr[start:stop] = xxx
+				return evalSliceAssignExpression(a, slice, name, leftVals, scope, val)
+			}
+
 			index := Eval(nodeType.Index, scope)
 			if index == NIL {
 				ret = NIL
 				return
 			}
 
-			idx := int64(index.(*Number).Value)
-			if idx < 0 {
-				return newError(a.Pos().Sline(), ERR_INDEX, idx)
+			idx, ok := toInt64(index)
+			if !ok {
+				return newError(a.Pos().Sline(), ERR_INDEXTYPE, index.Type())
+			}
+			if idx < 0 { //arr[-1] = xxx
+				i, ok := normalizeIndex(idx, int64(len(leftVals)))
+				if !ok {
+					return newError(a.Pos().Sline(), ERR_INDEX, idx)
+				}
+				idx = i
 			}
 
 			if idx < int64(len(leftVals)) { //index is in range
@@ -1447,6 +3056,52 @@ func evalArrayAssignExpression(a *ast.AssignExpression, name string, left Object
 	return newError(a.Pos().Sline(), ERR_INFIXOP, left.Type(), a.Token.Literal, val.Type())
 }
 
+//arr[start:stop] = xxx, replaces the elements of the slice with the elements of 'val',
+//so the array may grow or shrink, e.g. arr[1:3] = [x]
+//arr[start:stop:step] = xxx, 'val' must have the same number of elements as the slice.
+func evalSliceAssignExpression(a *ast.AssignExpression, slice *ast.SliceExpression, name string, leftVals []Object, scope *Scope, val Object) Object {
+	line := a.Pos().Sline()
+
+	var vals []Object
+	switch v := val.(type) {
+	case *Array:
+		vals = v.Members
+	case *Tuple:
+		vals = v.Members
+	default:
+		return newError(line, ERR_SLICEASSIGN, val.Type())
+	}
+
+	start, stop, step, err := sliceBounds(line, slice, len(leftVals), scope)
+	if err != nil {
+		return err
+	}
+
+	if step == 1 {
+		if stop < start {
+			stop = start
+		}
+		members := make([]Object, 0, len(leftVals)-(stop-start)+len(vals))
+		members = append(members, leftVals[:start]...)
+		members = append(members, vals...)
+		members = append(members, leftVals[stop:]...)
+		ret := &Array{Members: members}
+		scope.Set(name, ret)
+		return ret
+	}
+
+	indices := sliceIndices(start, stop, step)
+	if len(indices) != len(vals) {
+		return newError(line, ERR_SLICELEN, len(vals), len(indices))
+	}
+	for i, idx := range indices {
+		leftVals[idx] = vals[i]
+	}
+	ret := &Array{Members: leftVals}
+	scope.Set(name, ret)
+	return ret
+}
+
 //tuple element can not be assigned
 func evalTupleAssignExpression(a *ast.AssignExpression, name string, left Object, scope *Scope, val Object) (ret Object) {
 	//Tuple is an immutable sequence of values
@@ -1460,12 +3115,24 @@ func evalTupleAssignExpression(a *ast.AssignExpression, name string, left Object
 //hash[key] = value
 func evalHashAssignExpression(a *ast.AssignExpression, name string, left Object, scope *Scope, val Object) (ret Object) {
 	leftHash := left.(*Hash)
+	if leftHash.Frozen {
+		return newError(a.Pos().Sline(), ERR_FROZEN, left.Type())
+	}
+
 	switch a.Token.Literal {
 	case "=":
 		switch nodeType := a.Name.(type) {
 		case *ast.IndexExpression: //hashObj[key] = val
+			if _, ok := nodeType.Index.(*ast.SliceExpression); ok {
+				return newError(a.Pos().Sline(), ERR_SLICETYPE, left.Type())
+			}
 			key := Eval(nodeType.Index, scope)
-			leftHash.push(a.Pos().Sline(), key, val)
+			if key.Type() == ERROR_OBJ {
+				return key
+			}
+			if r := leftHash.push(a.Pos().Sline(), key, val); isError(r) {
+				return r
+			}
 			return leftHash
 		case *ast.Identifier: //hashObj.key = val
 			key := strings.Split(a.Name.String(), ".")[1]
@@ -1608,6 +3275,8 @@ func evalForEachArrayExpression(fal *ast.ForEachArrayLoop, scope *Scope) Object
 	} else if aValue.Type() == TUPLE_OBJ {
 		tuple, _ := aValue.(*Tuple)
 		members = tuple.Members
+	} else if aValue.Type() == ENUM_OBJ {
+		members, _ = spreadMembers(aValue)
 	} else if aValue.Type() == GO_OBJ { //go object
 		goObj := aValue.(*GoObject)
 		arr := goValueToObject(goObj.obj).(*Array)
@@ -1620,10 +3289,12 @@ func evalForEachArrayExpression(fal *ast.ForEachArrayLoop, scope *Scope) Object
 
 	arr := &Array{}
 	defer func() {
-		scope.Del(fal.Var)
+		delLoopVar(fal.Var, fal.Pattern, scope)
 	}()
 	for _, value := range members {
-		scope.Set(fal.Var, value)
+		if err := setLoopVar(fal.Pos().Sline(), fal.Var, fal.Pattern, value, scope); err != nil {
+			return err
+		}
 
 		result := Eval(fal.Block, scope)
 		if result.Type() == ERROR_OBJ {
@@ -1647,6 +3318,34 @@ func evalForEachArrayExpression(fal *ast.ForEachArrayLoop, scope *Scope) Object
 	return arr
 }
 
+//setLoopVar sets a foreach loop's variable, 'pattern' is nil unless the variable is
+//destructured, e.g. for [a, b] in xxx. Unlike the errors in the loop's block, a value
+//which does not match the pattern is returned as is, so the loop fails with it.
+func setLoopVar(line string, name string, pattern ast.Expression, val Object, scope *Scope) Object {
+	if pattern != nil {
+		return bindPattern(pattern, val, scope, letBinder(scope))
+	}
+	if name != "_" {
+		if err := checkAssignable(line, name, scope, true); err != nil {
+			return err
+		}
+		scope.Set(name, val)
+	}
+	return nil
+}
+
+func delLoopVar(name string, pattern ast.Expression, scope *Scope) {
+	if pattern == nil {
+		if name != "_" {
+			scope.Del(name)
+		}
+		return
+	}
+	for _, n := range patternNames(pattern) {
+		scope.Del(n)
+	}
+}
+
 //for index, value in string
 //for index, value in array
 //for index, value in tuple
@@ -1665,6 +3364,8 @@ func evalForEachArrayWithIndex(fml *ast.ForEachMapLoop, val Object, scope *Scope
 	} else if val.Type() == TUPLE_OBJ {
 		tuple, _ := val.(*Tuple)
 		members = tuple.Members
+	} else if val.Type() == ENUM_OBJ {
+		members, _ = spreadMembers(val)
 	}
 
 	if len(members) == 0 {
@@ -1673,19 +3374,15 @@ func evalForEachArrayWithIndex(fml *ast.ForEachMapLoop, val Object, scope *Scope
 
 	arr := &Array{}
 	defer func() {
-		if fml.Key != "_" {
-			scope.Del(fml.Key)
-		}
-		if fml.Value != "_" {
-			scope.Del(fml.Value)
-		}
+		delLoopVar(fml.Key, fml.KeyPattern, scope)
+		delLoopVar(fml.Value, fml.ValuePattern, scope)
 	}()
 	for idx, value := range members {
-		if fml.Key != "_" {
-			scope.Set(fml.Key, NewNumber(float64(idx)))
+		if err := setLoopVar(fml.Pos().Sline(), fml.Key, fml.KeyPattern, NewInteger(int64(idx)), scope); err != nil {
+			return err
 		}
-		if fml.Value != "_" {
-			scope.Set(fml.Value, value)
+		if err := setLoopVar(fml.Pos().Sline(), fml.Value, fml.ValuePattern, value, scope); err != nil {
+			return err
 		}
 
 		result := Eval(fml.Block, scope)
@@ -1737,7 +3434,7 @@ func evalForEachMapExpression(fml *ast.ForEachMapLoop, scope *Scope) Object { //
 	//for index, value in arr
 	//for index, value in string
 	//for index, value in tuple
-	if aValue.Type() == STRING_OBJ || aValue.Type() == ARRAY_OBJ || aValue.Type() == TUPLE_OBJ {
+	if aValue.Type() == STRING_OBJ || aValue.Type() == ARRAY_OBJ || aValue.Type() == TUPLE_OBJ || aValue.Type() == ENUM_OBJ {
 		return evalForEachArrayWithIndex(fml, aValue, scope)
 	}
 
@@ -1748,22 +3445,18 @@ func evalForEachMapExpression(fml *ast.ForEachMapLoop, scope *Scope) Object { //
 
 	arr := &Array{}
 	defer func() {
-		if fml.Key != "_" {
-			scope.Del(fml.Key)
-		}
-		if fml.Value != "_" {
-			scope.Del(fml.Value)
-		}
+		delLoopVar(fml.Key, fml.KeyPattern, scope)
+		delLoopVar(fml.Value, fml.ValuePattern, scope)
 	}()
 
 	//for _, pair := range hash.Pairs {
 	for _, hk := range hash.Order {
 		pair, _ := hash.Pairs[hk]
-		if fml.Key != "_" {
-			scope.Set(fml.Key, pair.Key)
+		if err := setLoopVar(fml.Pos().Sline(), fml.Key, fml.KeyPattern, pair.Key, scope); err != nil {
+			return err
 		}
-		if fml.Value != "_" {
-			scope.Set(fml.Value, pair.Value)
+		if err := setLoopVar(fml.Pos().Sline(), fml.Value, fml.ValuePattern, pair.Value, scope); err != nil {
+			return err
 		}
 
 		result := Eval(fml.Block, scope)
@@ -1973,14 +3666,14 @@ func _evalDecorator(node *ast.DecoratorExpr, scope *Scope) (string, Object, Obje
 	switch decorated := node.Decorated.(type) {
 	case *ast.FunctionLiteral:
 		decoratedFn := &Function{Literal: decorated, Scope: scope}
-		return name, applyFunction(decorated.Pos().Sline(), scope, decoratorFn, []Object{decoratedFn}), nil
+		return name, applyFunction(decorated.Pos().Sline(), scope, decoratorFn, []Object{decoratedFn}, nil), nil
 	case *ast.DecoratorExpr:
 		// eval the last decorator first
 		name, decoratedFn, err := _evalDecorator(decorated, scope)
 		if isError(err) {
 			return "", nil, err
 		}
-		return name, applyFunction(node.Pos().Sline(), scope, decoratorFn, append([]Object{decoratedFn})), nil
+		return name, applyFunction(node.Pos().Sline(), scope, decoratorFn, append([]Object{decoratedFn}), nil), nil
 	}
 
 	//should never reach here
@@ -2012,25 +3705,7 @@ func getVariadicArgs(call *ast.CallExpression, args []Object) []Object {
 		return []Object{errObj}
 	}
 
-	var members []Object
-	if lastArg.Type() == STRING_OBJ {
-		aStr, _ := lastArg.(*String)
-		runes := []rune(aStr.String)
-		for _, rune := range runes {
-			members = append(members, NewString(string(rune)))
-		}
-	} else if lastArg.Type() == ARRAY_OBJ {
-		arr, _ := lastArg.(*Array)
-		members = arr.Members
-	} else if lastArg.Type() == TUPLE_OBJ {
-		tuple, _ := lastArg.(*Tuple)
-		members = tuple.Members
-	} else if lastArg.Type() == GO_OBJ { //go object
-		goObj := lastArg.(*GoObject)
-		arr := goValueToObject(goObj.obj).(*Array)
-		members = arr.Members
-	}
-
+	members, _ := spreadMembers(lastArg)
 	args = args[:len(args)-1]
 	for _, m := range members {
 		args = append(args, m)
@@ -2039,44 +3714,74 @@ func getVariadicArgs(call *ast.CallExpression, args []Object) []Object {
 	return args
 }
 
-func evalCallExpression(node *ast.CallExpression, funcObj Object, scope *Scope) Object {
-	var args []Object
-	if len(node.Arguments) == 1 && node.Arguments[0].TokenLiteral() == ALL_ARGS {
-		if arr, ok := scope.Get(ALL_ARGS); ok {
-			for _, v := range arr.(*Array).Members {
-				args = append(args, v)
-			}
-		}
-	} else {
-		args = evalExpressions(node.Arguments, scope)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
+//keyword arguments of a call, e.g. connect("db", timeout: 5)
+type namedArgs struct {
+	names  []string
+	values []Object
+}
+
+func (na *namedArgs) len() int {
+	if na == nil {
+		return 0
+	}
+	return len(na.names)
+}
+
+//fieldNames returns the names of the keyword arguments, nil if there is none.
+func (na *namedArgs) fieldNames() []string {
+	if na == nil {
+		return nil
+	}
+	return na.names
+}
+
+//evalArguments evaluates the positional(unboxing the '...' one) and keyword arguments of a call.
+func evalArguments(call *ast.CallExpression, scope *Scope) ([]Object, *namedArgs, Object) {
+	args := evalExpressions(call.Arguments, scope)
+	if len(args) == 1 && isError(args[0]) {
+		return nil, nil, args[0]
 	}
 
-	if node.Variadic {
-		args = getVariadicArgs(node, args)
+	if call.Variadic {
+		args = getVariadicArgs(call, args)
 		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+			return nil, nil, args[0]
 		}
 	}
 
-	//check if it is a struct call
-	if structStmt, ok := scope.GetStruct(node.Function.String()); ok {
-		structObj := createStructObj(structStmt, scope)
-		//check if the struct has 'init' function
-		if _, ok := structObj.Scope.Get("init"); !ok {
-			if len(args) > 0 { //No "init" constructor,but has arguments passed.
-				return newError(node.Pos().Sline(), ERR_NOCONSTRUCTOR, len(args))
-			}
-			return structObj
+	if len(call.NamedArgs) == 0 {
+		return args, nil, nil
+	}
+
+	named := &namedArgs{}
+	for _, na := range call.NamedArgs {
+		v := Eval(na.Value, scope)
+		if isError(v) {
+			return nil, nil, v
 		}
-		//call `init` constructor, then return the struct object
-		r := structObj.CallMethod(node.Pos().Sline(), scope, "init", args...)
-		if r.Type() == ERROR_OBJ {
-			return r //return error object
+		named.names = append(named.names, na.Name.Value)
+		named.values = append(named.values, v)
+	}
+	return args, named, nil
+}
+
+func evalCallExpression(node *ast.CallExpression, funcObj Object, scope *Scope) Object {
+	var args []Object
+	var named *namedArgs
+	if len(node.Arguments) == 1 && node.Arguments[0].TokenLiteral() == ALL_ARGS {
+		//forward all the arguments, including the keyword ones
+		args, named = scope.getAllArgs()
+	} else {
+		var err Object
+		args, named, err = evalArguments(node, scope)
+		if err != nil {
+			return err
 		}
-		return structObj
+	}
+
+	//check if it is a struct call
+	if t, ok := scope.GetStruct(node.Function.String()); ok {
+		return newStructObj(node.Pos().Sline(), t, args, named)
 	}
 
 	var function Object
@@ -2089,13 +3794,24 @@ func evalCallExpression(node *ast.CallExpression, funcObj Object, scope *Scope)
 		}
 	}
 
-	return applyFunction(node.Pos().Sline(), scope, function, args)
+	if _, ok := function.(*Builtin); ok && named != nil {
+		return newError(node.Pos().Sline(), ERR_NOKWARGS, node.Function.String())
+	}
+
+	return applyFunction(node.Pos().Sline(), scope, function, args, named)
 }
 
-func applyFunction(line string, scope *Scope, fn Object, args []Object) Object {
+func applyFunction(line string, scope *Scope, fn Object, args []Object, named *namedArgs) (ret Object) {
 	switch fn := fn.(type) {
 	case *Function:
-		extendedScope := extendFunctionScope(fn, args)
+		extendedScope, err := extendFunctionScope(line, fn, args, named)
+		if err != nil {
+			return err
+		}
+		defer func() { //run the calls queued by 'defer', including the ones of the tail calls
+			ret = runDeferred(extendedScope, ret)
+		}()
+
 		evaluated := Eval(fn.Literal.Body, extendedScope)
 		if evaluated.Type() == TAIL_OBJ {
 			call := evaluated.(*TailCall).tail.Call.(*ast.CallExpression)
@@ -2104,16 +3820,9 @@ func applyFunction(line string, scope *Scope, fn Object, args []Object) Object {
 			needContinue := true
 			//expands out a recursive function into a flat for-loop control structure
 			for needContinue {
-				args2 := evalExpressions(call.Arguments, extendedScope)
-				if len(args2) == 1 && isError(args2[0]) {
-					return args2[0]
-				}
-
-				if call.Variadic {
-					args2 = getVariadicArgs(call, args2) //unboxing
-					if len(args2) == 1 && isError(args2[0]) {
-						return args2[0]
-					}
+				args2, named2, err := evalArguments(call, extendedScope)
+				if err != nil {
+					return err
 				}
 
 				function := Eval(call.Function, extendedScope)
@@ -2122,29 +3831,17 @@ func applyFunction(line string, scope *Scope, fn Object, args []Object) Object {
 				}
 
 				fn2 := function.(*Function)
-				argObjTable := make(map[string]Object)
-				for i, identNode := range fn2.Literal.Parameters {
-					argObjTable[identNode.Value] = args2[i]
-				}
 
 				//This is the most important part. we reuse the scope
 				// and not making a new scope.
-				extendedScope.store = argObjTable
+				extendedScope.store = make(map[string]Object)
 				extendedScope.parentScope = fn2.Scope
 				extendedScope.Writer = scope.Writer
-
-				if fn2.Literal.Variadic { //boxing
-					ellipsisArgs := args2[len(fn2.Literal.Parameters)-1:]
-					newArgs := make([]Object, 0, len(fn2.Literal.Parameters)+1)
-					newArgs = append(newArgs, args2[:len(fn2.Literal.Parameters)-1]...)
-					args2 = append(newArgs, &Array{Members: ellipsisArgs})
-					for i, arg := range args2 {
-						extendedScope.Set(fn2.Literal.Parameters[i].String(), arg)
-					}
+				extendedScope.namedArgs = nil
+				if err := bindArguments(call.Pos().Sline(), extendedScope, fn2, args2, named2); err != nil {
+					return err
 				}
 
-				extendedScope.Set(ALL_ARGS, &Array{Members: args2})
-
 				o = Eval(fn2.Literal.Body, extendedScope)
 				if o.Type() == ERROR_OBJ {
 					return o
@@ -2162,28 +3859,190 @@ func applyFunction(line string, scope *Scope, fn Object, args []Object) Object {
 		}
 	case *Builtin:
 		return fn.Fn(line, scope, args...)
+	case *StructType: //e.g. t = Animal; t("cat")
+		return newStructObj(line, fn, args, named)
 	default:
 		return newError(line, ERR_NOTFUNCTION, fn.Type())
 	}
 }
 
-func extendFunctionScope(fn *Function, args []Object) *Scope {
+//deferredCall is a call queued by the 'defer' statement.
+type deferredCall struct {
+	line   string
+	fn     Object //the function, when 'recv' is nil
+	recv   Object //the receiver of a method call
+	method string
+	args   []Object
+	named  *namedArgs
+}
+
+func (dc *deferredCall) call(scope *Scope) Object {
+	switch recv := dc.recv.(type) {
+	case nil:
+		return applyFunction(dc.line, scope, dc.fn, dc.args, dc.named)
+	case *Struct:
+		return recv.callMethod(dc.line, scope, dc.method, dc.args, dc.named)
+	case *Super:
+		return recv.Self.callMethodOf(dc.line, recv.Class, dc.method, dc.args, dc.named)
+	default:
+		return recv.CallMethod(dc.line, scope, dc.method, dc.args...)
+	}
+}
+
+//runDeferred runs the calls queued on the function's frame in LIFO order, after the function
+//returned 'result'. The deferred calls see the return value as '$result', and can change it by
+//assigning to '$result'. When the function failed with an error or a throw, '$result' is nil
+//and the failure is kept. A failure in a deferred call replaces the result.
+func runDeferred(frame *Scope, result Object) Object {
+	if len(frame.deferred) == 0 {
+		return result
+	}
+
+	failed := result.Type() == ERROR_OBJ || result.Type() == THROW_OBJ
+	if failed {
+		frame.store[DEFER_RESULT] = NIL
+	} else {
+		frame.store[DEFER_RESULT] = result
+	}
+
+	for len(frame.deferred) > 0 {
+		last := len(frame.deferred) - 1
+		dc := frame.deferred[last]
+		frame.deferred = frame.deferred[:last]
+
+		r := dc.call(frame)
+		if r.Type() == ERROR_OBJ || r.Type() == THROW_OBJ {
+			result, failed = r, true
+		}
+	}
+
+	if !failed {
+		result = frame.store[DEFER_RESULT]
+	}
+	delete(frame.store, DEFER_RESULT)
+	return result
+}
+
+func extendFunctionScope(line string, fn *Function, args []Object, named *namedArgs) (*Scope, Object) {
 	scope := NewScope(fn.Scope, nil)
+	if err := bindArguments(line, scope, fn, args, named); err != nil {
+		return nil, err
+	}
+	return scope, nil
+}
+
+//bindArguments binds the arguments of a call to the parameters of 'fn' in 'scope':
+//  1. positional arguments are bound in order, the extra ones are boxed into the variadic parameter.
+//  2. keyword arguments are matched by name.
+//  3. the remaining parameters get their default values. Default values are evaluated
+//     at call time in the function's scope, so they can refer to the parameters before
+//     them, e.g. fn f(x, y = x * 2)
+//  4. the destructured parameters are bound to the names of their patterns.
+//A function which uses '$_' accepts any keyword arguments, so it can forward them
+//to another function, e.g. a decorator's wrapper function.
+func bindArguments(line string, scope *Scope, fn *Function, args []Object, named *namedArgs) Object {
+	params := fn.Literal.Parameters
+	nFixed := len(params) //number of non-variadic parameters
+	if fn.Literal.Variadic {
+		nFixed--
+	}
+
+	//a function which uses '$_' may get more arguments than its parameters
+	got := len(args) + named.len()
+	if len(args) > nFixed && !fn.Literal.Variadic && !fn.Literal.AllArgs {
+		return arityError(line, fn, got)
+	}
+
+	for i := 0; i < nFixed && i < len(args); i++ {
+		scope.Set(params[i].Value, args[i])
+	}
+	nPositional := len(args) //before boxing the variadic arguments
 	if fn.Literal.Variadic { //boxing
-		ellipsisArgs := args[len(fn.Literal.Parameters)-1:]
-		newArgs := make([]Object, 0, len(fn.Literal.Parameters)+1)
-		newArgs = append(newArgs, args[:len(fn.Literal.Parameters)-1]...)
-		args = append(newArgs, &Array{Members: ellipsisArgs})
-		for i, arg := range args {
-			scope.Set(fn.Literal.Parameters[i].String(), arg)
-		}
-	} else {
-		for paramIdx, param := range fn.Literal.Parameters {
-			scope.Set(param.Value, args[paramIdx])
+		ellipsisArgs := []Object{}
+		if len(args) > nFixed {
+			ellipsisArgs = args[nFixed:]
+			args = append(args[:nFixed:nFixed], &Array{Members: ellipsisArgs})
+		} else {
+			args = append(args[:len(args):len(args)], &Array{Members: ellipsisArgs})
 		}
+		scope.Set(params[nFixed].Value, &Array{Members: ellipsisArgs})
 	}
 	scope.Set(ALL_ARGS, &Array{Members: args})
-	return scope
+
+	for i := 0; i < named.len(); i++ {
+		name := named.names[i]
+		idx := -1
+		for j := 0; j < nFixed; j++ {
+			if params[j].Value == name {
+				idx = j
+				break
+			}
+		}
+
+		if idx == -1 {
+			if fn.Literal.AllArgs {
+				continue
+			}
+			return newError(line, ERR_KWARGUNKNOWN, name, fn.name())
+		}
+		if idx < nPositional {
+			return newError(line, ERR_KWARGDUP, fn.name(), name)
+		}
+		scope.Set(name, named.values[i])
+	}
+	if fn.Literal.AllArgs {
+		scope.namedArgs = named
+	}
+
+	for i := 0; i < nFixed; i++ {
+		if _, ok := scope.store[params[i].Value]; ok {
+			continue
+		}
+		value, ok := fn.Literal.Values[params[i].Value]
+		if !ok {
+			return arityError(line, fn, got)
+		}
+		v := Eval(value, scope)
+		if isError(v) {
+			return v
+		}
+		scope.Set(params[i].Value, v)
+	}
+
+	for _, param := range params { //destructured parameters, e.g. fn f((x, y))
+		pattern, ok := fn.Literal.Patterns[param.Value]
+		if !ok {
+			continue
+		}
+		v := scope.store[param.Value]
+		scope.Del(param.Value)
+		if err := bindPattern(pattern, v, scope, letBinder(scope)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//arityError reports a call with the wrong number of arguments, e.g.
+//    connect(): wrong number of arguments. expected=1 to 3, got=0
+func arityError(line string, fn *Function, got int) Object {
+	nFixed := len(fn.Literal.Parameters)
+	if fn.Literal.Variadic {
+		nFixed--
+	}
+	nRequired := nFixed - len(fn.Literal.Values)
+
+	var expected string
+	switch {
+	case fn.Literal.Variadic:
+		expected = fmt.Sprintf("at least %d", nRequired)
+	case nRequired != nFixed:
+		expected = fmt.Sprintf("%d to %d", nRequired, nFixed)
+	default:
+		expected = fmt.Sprint(nFixed)
+	}
+	return newError(line, "%s(): "+ERR_ARGUMENT, fn.name(), expected, got)
 }
 
 func unwrapReturnValue(obj Object) Object {
@@ -2213,6 +4072,12 @@ func objectToNativeBoolean(o Object) bool {
 			return false
 		}
 		return true
+	case *Integer:
+		return obj.Value != 0
+	case *BigInt:
+		return obj.Value.Sign() != 0
+	case *Decimal:
+		return obj.Unscaled.Sign() != 0
 	case *String:
 		return obj.String != ""
 	case *Array:
@@ -2262,6 +4127,18 @@ func IsTrue(obj Object) bool {
 			if obj.(*Number).Value == 0.0 {
 				return false
 			}
+		case INTEGER_OBJ:
+			if obj.(*Integer).Value == 0 {
+				return false
+			}
+		case BIGINT_OBJ:
+			if obj.(*BigInt).Value.Sign() == 0 {
+				return false
+			}
+		case DECIMAL_OBJ:
+			if obj.(*Decimal).Unscaled.Sign() == 0 {
+				return false
+			}
 		case ARRAY_OBJ:
 			if len(obj.(*Array).Members) == 0 {
 				return false
@@ -2281,3 +4158,21 @@ func IsTrue(obj Object) bool {
 		return true
 	}
 }
+
+//Integer, Number, BigInt or Decimal
+func isNumeric(obj Object) bool {
+	switch obj.Type() {
+	case INTEGER_OBJ, NUMBER_OBJ, BIGINT_OBJ, DECIMAL_OBJ:
+		return true
+	}
+	return false
+}
+
+//BigInt or Decimal
+func isBigNumeric(obj Object) bool {
+	switch obj.Type() {
+	case BIGINT_OBJ, DECIMAL_OBJ:
+		return true
+	}
+	return false
+}
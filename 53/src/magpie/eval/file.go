@@ -56,12 +56,12 @@ func (f *FileObject) read(line string, args ...Object) Object {
 		return newError(line, ERR_ARGUMENT, "1", len(args))
 	}
 
-	readlen, ok := args[0].(*Number)
+	readlen, ok := toInt64(args[0])
 	if !ok {
-		return newError(line, ERR_PARAMTYPE, "first", "read", "*Number", args[0].Type())
+		return newError(line, ERR_PARAMTYPE, "first", "read", "*Integer", args[0].Type())
 	}
 
-	buffer := make([]byte, int(readlen.Value))
+	buffer := make([]byte, int(readlen))
 	n, err := f.File.Read(buffer)
 	if err != io.EOF && err != nil {
 		return newError(line, "'read' failed. reason: %s", err.Error())
@@ -106,7 +106,7 @@ func (f *FileObject) write(line string, args ...Object) Object {
 		return newError(line, "'write' failed. reason: %s", err.Error())
 	}
 
-	return NewNumber(float64(n))
+	return NewInteger(int64(n))
 }
 
 func (f *FileObject) writeString(line string, args ...Object) Object {
@@ -124,7 +124,7 @@ func (f *FileObject) writeString(line string, args ...Object) Object {
 		return newError(line, "'writeString' failed. reason: %s", err.Error())
 	}
 
-	return NewNumber(float64(ret))
+	return NewInteger(int64(ret))
 }
 
 func (f *FileObject) writeLine(line string, args ...Object) Object {
@@ -142,7 +142,7 @@ func (f *FileObject) writeLine(line string, args ...Object) Object {
 		return newError(line, "'writeLine' failed. reason: %s", err.Error())
 	}
 
-	return NewNumber(float64(ret))
+	return NewInteger(int64(ret))
 }
 
 func (f *FileObject) getName(line string, args ...Object) Object {
@@ -3,6 +3,8 @@ package eval
 import (
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"strings"
 )
@@ -45,6 +47,15 @@ func NewGoObject(obj interface{}) *GoObject {
 	return &GoObject{obj: obj, value: reflect.ValueOf(obj)}
 }
 
+//isNil reports whether the wrapped go value is a nil pointer, map, slice, etc.
+func (gobj *GoObject) isNil() bool {
+	switch gobj.value.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return gobj.value.IsNil()
+	}
+	return false
+}
+
 // wrapper for go functions
 type GoFuncObject struct {
 	name string
@@ -92,6 +103,37 @@ func ObjectToGoValue(obj Object, typ reflect.Type) reflect.Value {
 		default:
 			v = reflect.ValueOf(obj.Value)
 		}
+	case *Integer:
+		switch typ.Kind() {
+		case reflect.Int:
+			v = reflect.ValueOf(int(obj.Value))
+		case reflect.Int8:
+			v = reflect.ValueOf(int8(obj.Value))
+		case reflect.Int16:
+			v = reflect.ValueOf(int16(obj.Value))
+		case reflect.Int32:
+			v = reflect.ValueOf(int32(obj.Value))
+		case reflect.Uint:
+			v = reflect.ValueOf(uint(obj.Value))
+		case reflect.Uint8:
+			v = reflect.ValueOf(uint8(obj.Value))
+		case reflect.Uint16:
+			v = reflect.ValueOf(uint16(obj.Value))
+		case reflect.Uint32:
+			v = reflect.ValueOf(uint32(obj.Value))
+		case reflect.Uint64:
+			v = reflect.ValueOf(uint64(obj.Value))
+		case reflect.Float32:
+			v = reflect.ValueOf(float32(obj.Value))
+		case reflect.Float64:
+			v = reflect.ValueOf(float64(obj.Value))
+		default:
+			v = reflect.ValueOf(obj.Value)
+		}
+	case *BigInt:
+		v = reflect.ValueOf(obj.Value)
+	case *Decimal:
+		v = reflect.ValueOf(obj.Float64())
 	case *String:
 		v = reflect.ValueOf(obj.String)
 	case *Boolean:
@@ -108,6 +150,10 @@ func ObjectToGoValue(obj Object, typ reflect.Type) reflect.Value {
 
 // Go language Value to magpie language Object(take care of slice object value)
 func goValueToObject(v interface{}) Object {
+	if i, ok := v.(*big.Int); ok && i != nil {
+		return NewBigInt(i)
+	}
+
 	val := reflect.ValueOf(v)
 	kind := val.Kind()
 
@@ -121,9 +167,9 @@ func goValueToObject(v interface{}) Object {
 	case reflect.String:
 		return NewString(val.String())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return NewNumber(float64(val.Int()))
+		return NewInteger(val.Int())
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return NewNumber(float64(val.Uint()))
+		return uintToObject(val.Uint())
 	case reflect.Float32, reflect.Float64:
 		return NewNumber(val.Float())
 	case reflect.Bool:
@@ -139,6 +185,14 @@ func goValueToObject(v interface{}) Object {
 	}
 }
 
+//uintToObject returns an integer, or a bigint if 'u' does not fit in int64.
+func uintToObject(u uint64) Object {
+	if u > math.MaxInt64 {
+		return NewBigInt(new(big.Int).SetUint64(u))
+	}
+	return NewInteger(int64(u))
+}
+
 func callGoMethod(line string, methodVal reflect.Value, args ...Object) (ret Object) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -166,9 +220,9 @@ func callGoMethod(line string, methodVal reflect.Value, args ...Object) (ret Obj
 		case reflect.String:
 			results = append(results, NewString(retVal.String()))
 		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			results = append(results, NewNumber(float64(retVal.Int())))
+			results = append(results, NewInteger(retVal.Int()))
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			results = append(results, NewNumber(float64(retVal.Uint())))
+			results = append(results, uintToObject(retVal.Uint()))
 		case reflect.Float64, reflect.Float32:
 			results = append(results, NewNumber(retVal.Float()))
 		default:
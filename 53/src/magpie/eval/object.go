@@ -6,6 +6,7 @@ import (
 	"hash/fnv"
 	"magpie/ast"
 	"math"
+	"math/big"
 	"os"
 	"reflect"
 	"regexp"
@@ -19,6 +20,7 @@ type ObjectType string
 
 const (
 	NUMBER_OBJ       = "NUMBER"
+	INTEGER_OBJ      = "INTEGER"
 	NIL_OBJ          = "NIL_OBJ"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	STRING_OBJ       = "STRING"
@@ -38,6 +40,11 @@ const (
 	FILE_OBJ         = "FILE"
 	OS_OBJ           = "OS_OBJ"
 	STRUCT_OBJ       = "STRUCT"
+	STRUCT_TYPE_OBJ  = "STRUCT_TYPE"
+	SUPER_OBJ        = "SUPER"
+	INTERFACE_OBJ    = "INTERFACE"
+	ENUM_OBJ         = "ENUM"
+	ENUM_MEMBER_OBJ  = "ENUM_MEMBER"
 	THROW_OBJ        = "THROW"
 	TAIL_OBJ         = "TAIL_OBJ"
 	CMD_OBJ          = "CMD_OBJ"
@@ -65,6 +72,21 @@ type Hashable interface {
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
+	Index int //position in the bucket of the struct keys which have the same '__hash__', see Hash.lookup
+}
+
+//hashKeyOf returns the hash key of 'key', struct objects(also as members of tuples)
+//are hashed with '__hash__', which may fail.
+func hashKeyOf(line string, key Object) (HashKey, Object) {
+	switch k := key.(type) {
+	case *Struct:
+		return k.hashKey(line)
+	case *Tuple:
+		return k.hashKey(line)
+	case Hashable:
+		return k.HashKey(), nil
+	}
+	return HashKey{}, newError(line, ERR_KEY, key.Type())
 }
 
 type Number struct {
@@ -78,7 +100,15 @@ func (n *Number) Inspect() string {
 func (n *Number) Type() ObjectType { return NUMBER_OBJ }
 
 func (n *Number) HashKey() HashKey {
-	return HashKey{Type: n.Type(), Value: uint64(n.Value)}
+	//A number which has an integral value(e.g. 1.0, 1e30) should be the same
+	//key as the integer(e.g. 1) or the bigint(e.g. 10n ** 30), because they are equal.
+	if isInt64(n.Value) {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(n.Value))}
+	}
+	if i, ok := floatToBigInt(n.Value); ok {
+		return (&BigInt{Value: i}).HashKey()
+	}
+	return HashKey{Type: n.Type(), Value: math.Float64bits(n.Value)}
 }
 
 func (n *Number) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
@@ -97,6 +127,8 @@ func (n *Number) CallMethod(line string, scope *Scope, method string, args ...Ob
 		return n.round(line, args...)
 	case "str":
 		return n.str(line, args...)
+	case "int":
+		return n.int(line, args...)
 	}
 	return newError(line, ERR_NOMETHOD, method, n.Type())
 }
@@ -139,7 +171,10 @@ func (n *Number) pow(line string, args ...Object) Object {
 		return newError(line, ERR_ARGUMENT, "1", len(args))
 	}
 
-	temp := args[0].(*Number).Value
+	temp, ok := toFloat64(args[0])
+	if !ok {
+		return newError(line, ERR_PARAMTYPE, "first", "pow", "*Number", args[0].Type())
+	}
 	return NewNumber(math.Pow(n.Value, temp))
 }
 
@@ -148,7 +183,10 @@ func (n *Number) round(line string, args ...Object) Object {
 		return newError(line, ERR_ARGUMENT, "1", len(args))
 	}
 
-	precision := int64(args[0].(*Number).Value)
+	precision, ok := toInt64(args[0])
+	if !ok {
+		return newError(line, ERR_PARAMTYPE, "first", "round", "*Integer", args[0].Type())
+	}
 
 	format := fmt.Sprintf("%%.%df", precision)    //'%.xf', x is the precision, e.g. %.2f
 	resultStr := fmt.Sprintf(format, n.Value)     //convert to string
@@ -159,19 +197,238 @@ func (n *Number) round(line string, args ...Object) Object {
 	return NewNumber(ret)
 }
 
+//n.str(), n.str(16)
 func (n *Number) str(line string, args ...Object) Object {
 	argLen := len(args)
-	if argLen != 0 {
-		return newError(line, ERR_ARGUMENT, "0", argLen)
+	if argLen > 1 {
+		return newError(line, ERR_ARGUMENT, "0|1", argLen)
+	}
+
+	if argLen == 0 {
+		return NewString(fmt.Sprintf("%g", n.Value))
+	}
+
+	base, errObj := getBase(line, "str", args[0])
+	if errObj != nil {
+		return errObj
+	}
+	if base == 10 {
+		return NewString(fmt.Sprintf("%g", n.Value))
+	}
+	i, ok := floatToBigInt(n.Value)
+	if !ok {
+		return newError(line, "can not convert '%g' to base %d, it is not an integral value", n.Value, base)
+	}
+	return NewString(i.Text(base))
+}
+
+func (n *Number) int(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
 	}
 
-	return NewString(fmt.Sprintf("%g", n.Value))
+	if !isInt64(math.Trunc(n.Value)) { //NaN, Inf or out of range
+		return newError(line, ERR_OVERFLOW, n.Inspect(), INTEGER_OBJ)
+	}
+	return NewInteger(int64(n.Value))
+}
+
+//isInt64 reports whether 'f' is an integral value in the range of int64(2^63 is not).
+func isInt64(f float64) bool {
+	return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+}
+
+//floatToBigInt converts 'f' to a bigint, it fails if 'f' is not a finite integral value.
+func floatToBigInt(f float64) (*big.Int, bool) {
+	if f != math.Trunc(f) || math.IsInf(f, 0) {
+		return nil, false
+	}
+	i, _ := big.NewFloat(f).Int(nil)
+	return i, true
 }
 
 func NewNumber(f float64) *Number {
 	return &Number{Value: f}
 }
 
+type Integer struct {
+	Value int64
+}
+
+func (i *Integer) Inspect() string {
+	return strconv.FormatInt(i.Value, 10)
+}
+
+func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (i *Integer) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
+	switch method {
+	case "ceil", "floor", "trunc":
+		if len(args) != 0 {
+			return newError(line, ERR_ARGUMENT, "0", len(args))
+		}
+		return i
+	case "sqrt":
+		return i.sqrt(line, args...)
+	case "pow":
+		return i.pow(line, args...)
+	case "str":
+		return i.str(line, args...)
+	case "float":
+		return i.float(line, args...)
+	}
+	return newError(line, ERR_NOMETHOD, method, i.Type())
+}
+
+func (i *Integer) sqrt(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
+	}
+
+	return NewNumber(math.Sqrt(float64(i.Value)))
+}
+
+func (i *Integer) pow(line string, args ...Object) Object {
+	if len(args) != 1 {
+		return newError(line, ERR_ARGUMENT, "1", len(args))
+	}
+
+	switch exp := args[0].(type) {
+	case *Integer:
+		if exp.Value >= 0 {
+			if result, ok := powInt64(i.Value, exp.Value); ok {
+				return NewInteger(result)
+			}
+			return NewBigInt(new(big.Int).Exp(big.NewInt(i.Value), big.NewInt(exp.Value), nil))
+		}
+		return NewNumber(math.Pow(float64(i.Value), float64(exp.Value)))
+	case *Number:
+		return NewNumber(math.Pow(float64(i.Value), exp.Value))
+	}
+	return newError(line, ERR_PARAMTYPE, "first", "pow", "*Number", args[0].Type())
+}
+
+//i.str(), i.str(16)
+func (i *Integer) str(line string, args ...Object) Object {
+	argLen := len(args)
+	if argLen > 1 {
+		return newError(line, ERR_ARGUMENT, "0|1", argLen)
+	}
+
+	base := 10
+	if argLen == 1 {
+		var errObj Object
+		if base, errObj = getBase(line, "str", args[0]); errObj != nil {
+			return errObj
+		}
+	}
+	return NewString(strconv.FormatInt(i.Value, base))
+}
+
+func (i *Integer) float(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
+	}
+
+	return NewNumber(float64(i.Value))
+}
+
+func NewInteger(i int64) *Integer {
+	return &Integer{Value: i}
+}
+
+//getBase checks the 'base' argument of str(base), it must be between 2 and 36.
+func getBase(line string, method string, arg Object) (int, Object) {
+	base, ok := arg.(*Integer)
+	if !ok {
+		return 0, newError(line, ERR_PARAMTYPE, "first", method, "*Integer", arg.Type())
+	}
+	if base.Value < 2 || base.Value > 36 {
+		return 0, newError(line, ERR_RADIX, base.Value)
+	}
+	return int(base.Value), nil
+}
+
+//Checked int64 arithmetic, the returned bool is false if the result overflows.
+func addInt64(x, y int64) (int64, bool) {
+	z := x + y
+	return z, (z > x) == (y > 0)
+}
+
+func subInt64(x, y int64) (int64, bool) {
+	z := x - y
+	return z, (z < x) == (y > 0)
+}
+
+func mulInt64(x, y int64) (int64, bool) {
+	if x == 0 || y == 0 {
+		return 0, true
+	}
+	z := x * y
+	return z, z/y == x && !(x == math.MinInt64 && y == -1)
+}
+
+//x ** y, 'y' must not be negative
+func powInt64(x, y int64) (int64, bool) {
+	var result int64 = 1
+	var ok bool
+	for y > 0 {
+		if y&1 == 1 {
+			if result, ok = mulInt64(result, x); !ok {
+				return 0, false
+			}
+		}
+		y >>= 1
+		if y > 0 {
+			if x, ok = mulInt64(x, x); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+//toInt64 returns the integral value of a numeric object.
+func toInt64(obj Object) (int64, bool) {
+	switch o := obj.(type) {
+	case *Integer:
+		return o.Value, true
+	case *Number: //only an integral value in the range of int64, e.g. not 1.5 or 1e30
+		if isInt64(o.Value) {
+			return int64(o.Value), true
+		}
+	case *BigInt:
+		if o.Value.IsInt64() {
+			return o.Value.Int64(), true
+		}
+	case *Decimal:
+		if i := o.Trunc(); i.IsInt64() {
+			return i.Int64(), true
+		}
+	}
+	return 0, false
+}
+
+//toFloat64 returns the float value of a numeric object.
+func toFloat64(obj Object) (float64, bool) {
+	switch o := obj.(type) {
+	case *Integer:
+		return float64(o.Value), true
+	case *Number:
+		return o.Value, true
+	case *BigInt:
+		f, _ := new(big.Float).SetInt(o.Value).Float64()
+		return f, true
+	case *Decimal:
+		return o.Float64(), true
+	}
+	return 0, false
+}
+
 type Nil struct {
 }
 
@@ -297,12 +554,11 @@ func (s *String) set(line string, args ...Object) Object {
 		return newError(line, ERR_ARGUMENT, "2", argLen)
 	}
 
-	idxObj, ok := args[0].(*Number)
+	idx, ok := toInt64(args[0])
 	if !ok {
-		return newError(line, ERR_PARAMTYPE, "first", "set", "*Number", args[0].Type())
+		return newError(line, ERR_PARAMTYPE, "first", "set", "*Integer", args[0].Type())
 	}
 
-	idx := int64(idxObj.Value)
 	if idx < 0 || idx > int64(len(s.String)) {
 		return newError(line, ERR_INDEX, idx)
 	}
@@ -366,8 +622,17 @@ func (f *Function) CallMethod(line string, scope *Scope, method string, args ...
 	return newError(line, ERR_NOMETHOD, method, f.Type())
 }
 
+//name is used in error messages
+func (f *Function) name() string {
+	if f.Literal.Name == "" {
+		return "<anonymous>"
+	}
+	return f.Literal.Name
+}
+
 type Array struct {
 	Members []Object
+	Frozen  bool //set by 'freeze()'
 }
 
 func (a *Array) iter() bool       { return true }
@@ -390,6 +655,10 @@ func (a *Array) Inspect() string {
 }
 
 func (a *Array) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
+	if a.Frozen && method != "len" {
+		return newError(line, ERR_FROZEN, a.Type())
+	}
+
 	switch method {
 	case "len":
 		return a.len(line, args...)
@@ -407,7 +676,7 @@ func (a *Array) len(line string, args ...Object) Object {
 	if len(args) != 0 {
 		return newError(line, ERR_ARGUMENT, "0", len(args))
 	}
-	return NewNumber(float64(len(a.Members)))
+	return NewInteger(int64(len(a.Members)))
 }
 
 func (a *Array) pop(line string, args ...Object) Object {
@@ -420,7 +689,10 @@ func (a *Array) pop(line string, args ...Object) Object {
 		a.Members = a.Members[:last]
 		return popped
 	}
-	idx := int64(args[0].(*Number).Value)
+	idx, ok := toInt64(args[0])
+	if !ok {
+		return newError(line, ERR_PARAMTYPE, "first", "pop", "*Integer", args[0].Type())
+	}
 	if idx < 0 {
 		idx = idx + int64(last+1)
 	}
@@ -446,12 +718,11 @@ func (a *Array) set(line string, args ...Object) Object {
 		return newError(line, ERR_ARGUMENT, "2", len(args))
 	}
 
-	idxObj, ok := args[0].(*Number)
+	idx, ok := toInt64(args[0])
 	if !ok {
-		return newError(line, ERR_PARAMTYPE, "first", "set", "*Number", args[0].Type())
+		return newError(line, ERR_PARAMTYPE, "first", "set", "*Integer", args[0].Type())
 	}
 
-	idx := int64(idxObj.Value)
 	if idx < 0 || idx >= int64(len(a.Members)) {
 		oldLen := int64(len(a.Members))
 		for i := oldLen; i <= idx; i++ {
@@ -476,6 +747,7 @@ type Hash struct {
 	Pairs     map[HashKey]HashPair
 	IsOrdered bool
 	Order     []HashKey
+	Frozen    bool //set by 'freeze()'
 }
 
 func (h *Hash) iter() bool       { return true }
@@ -534,8 +806,14 @@ func (h *Hash) CallMethod(line string, scope *Scope, method string, args ...Obje
 	case "values":
 		return h.values(line, args...)
 	case "pop", "delete", "remove":
+		if h.Frozen {
+			return newError(line, ERR_FROZEN, h.Type())
+		}
 		return h.pop(line, args...)
 	case "push", "set":
+		if h.Frozen {
+			return newError(line, ERR_FROZEN, h.Type())
+		}
 		return h.push(line, args...)
 	case "get":
 		return h.get(line, args...)
@@ -579,43 +857,61 @@ func (h *Hash) pop(line string, args ...Object) Object {
 	if len(args) != 1 {
 		return newError(line, ERR_ARGUMENT, "1", len(args))
 	}
-	hashable, ok := args[0].(Hashable)
-	if !ok {
-		return newError(line, ERR_KEY, args[0].Type())
+	hk, ok, err := h.lookup(line, args[0])
+	if err != nil {
+		return err
 	}
 
-	hk := hashable.HashKey()
-	if hashPair, ok := h.Pairs[hashable.HashKey()]; ok {
-		// remove the 'key' from 'Order' array of Hash.
-		for idx, k := range h.Order {
-			r := reflect.DeepEqual(hk, k)
-			if r {
-				h.Order = append(h.Order[:idx], h.Order[idx+1:]...)
+	if ok {
+		hashPair := h.Pairs[hk]
+		h.removeOrder(hk)
+		delete(h.Pairs, hk)
+
+		//keep the bucket of struct keys dense by moving the following keys forward
+		for next := (HashKey{Type: hk.Type, Value: hk.Value, Index: hk.Index + 1}); ; next.Index++ {
+			pair, ok := h.Pairs[next]
+			if !ok {
 				break
 			}
+			prev := next
+			prev.Index--
+			h.Pairs[prev] = pair
+			delete(h.Pairs, next)
+			for idx, k := range h.Order {
+				if k == next {
+					h.Order[idx] = prev
+					break
+				}
+			}
 		}
-
-		delete(h.Pairs, hk)
 		return hashPair.Value
 	}
 
 	return NIL
 }
 
+//removeOrder removes the key 'hk' from the 'Order' array of Hash.
+func (h *Hash) removeOrder(hk HashKey) {
+	for idx, k := range h.Order {
+		if k == hk {
+			h.Order = append(h.Order[:idx], h.Order[idx+1:]...)
+			break
+		}
+	}
+}
+
 func (h *Hash) push(line string, args ...Object) Object {
 	if len(args) != 2 {
 		return newError(line, ERR_ARGUMENT, "2", len(args))
 	}
-	if hashable, ok := args[0].(Hashable); ok {
-		hk := hashable.HashKey()
-		if _, exists := h.Pairs[hk]; !exists { //if key not exists
-			h.Order = append(h.Order, hk)
-		}
-
-		h.Pairs[hashable.HashKey()] = HashPair{Key: args[0], Value: args[1]}
-	} else {
-		return newError(line, ERR_KEY, args[0].Type())
+	hk, exists, err := h.lookup(line, args[0])
+	if err != nil {
+		return err
+	}
+	if !exists {
+		h.Order = append(h.Order, hk)
 	}
+	h.Pairs[hk] = HashPair{Key: args[0], Value: args[1]}
 
 	return h
 }
@@ -624,16 +920,45 @@ func (h *Hash) get(line string, args ...Object) Object {
 	if len(args) != 1 {
 		return newError(line, ERR_ARGUMENT, "1", len(args))
 	}
-	hashable, ok := args[0].(Hashable)
-	if !ok {
-		return newError(line, ERR_KEY, args[0].Type())
+	hk, ok, err := h.lookup(line, args[0])
+	if err != nil {
+		return err
 	}
-	if hashPair, ok := h.Pairs[hashable.HashKey()]; ok {
-		return hashPair.Value
+	if ok {
+		return h.Pairs[hk].Value
 	}
 	return NIL
 }
 
+//lookup returns the slot of 'key' in the hash, and whether the key is in it.
+//Struct keys which have the same '__hash__' are kept in a bucket(the slots with
+//the same Type and Value), where they are told apart with '__eq__'.
+func (h *Hash) lookup(line string, key Object) (HashKey, bool, Object) {
+	hk, err := hashKeyOf(line, key)
+	if err != nil {
+		return hk, false, err
+	}
+
+	s, ok := key.(*Struct)
+	if !ok || !s.hasMethod("__eq__") {
+		_, exists := h.Pairs[hk]
+		return hk, exists, nil
+	}
+	for ; ; hk.Index++ {
+		pair, ok := h.Pairs[hk]
+		if !ok {
+			return hk, false, nil
+		}
+		r := s.callMethod(line, s.Scope, "__eq__", []Object{pair.Key}, nil)
+		if isError(r) {
+			return hk, false, r
+		}
+		if IsTrue(r) {
+			return hk, true, nil
+		}
+	}
+}
+
 func NewTuple(isMulti bool) *Tuple {
 	//we assume tuple has at least two members
 	return &Tuple{IsMulti: isMulti, Members: []Object{NIL, NIL}}
@@ -688,7 +1013,7 @@ func (t *Tuple) len(line string, args ...Object) Object {
 	if len(args) != 0 {
 		return newError(line, ERR_ARGUMENT, "0", len(args))
 	}
-	return NewNumber(float64(len(t.Members)))
+	return NewInteger(int64(len(t.Members)))
 }
 
 func (t *Tuple) get(line string, args ...Object) Object {
@@ -696,12 +1021,11 @@ func (t *Tuple) get(line string, args ...Object) Object {
 		return newError(line, ERR_ARGUMENT, "1", len(args))
 	}
 
-	idxObj, ok := args[0].(*Number)
+	val, ok := toInt64(args[0])
 	if !ok {
-		return newError(line, ERR_PARAMTYPE, "first", "get", "*Number", args[0].Type())
+		return newError(line, ERR_PARAMTYPE, "first", "get", "*Integer", args[0].Type())
 	}
 
-	val := int64(idxObj.Value)
 	if val < 0 || val >= int64(len(t.Members)) {
 		return newError(line, ERR_INDEX, val)
 	}
@@ -721,17 +1045,22 @@ func (t *Tuple) empty(line string, args ...Object) Object {
 }
 
 func (t *Tuple) HashKey() HashKey {
+	hk, err := t.hashKey("")
+	if err != nil {
+		panic(err)
+	}
+	return hk
+}
+
+func (t *Tuple) hashKey(line string) (HashKey, Object) {
 	// https://en.wikipedia.org/wiki/Jenkins_hash_function
 	var hash uint64 = 0
 	for _, v := range t.Members {
-		hashable, ok := v.(Hashable)
-		if !ok {
-			errStr := fmt.Sprintf(ERR_KEY, v.Type())
-			panic(errStr)
+		h, err := hashKeyOf(line, v)
+		if err != nil {
+			return h, err
 		}
 
-		h := hashable.HashKey()
-
 		hash += h.Value
 		hash += hash << 10
 		hash ^= hash >> 6
@@ -740,7 +1069,7 @@ func (t *Tuple) HashKey() HashKey {
 	hash ^= hash >> 11
 	hash += hash << 15
 
-	return HashKey{Type: t.Type(), Value: hash}
+	return HashKey{Type: t.Type(), Value: hash}, nil
 }
 
 type Break struct{}
@@ -807,8 +1136,8 @@ func (ft *Formatter) Format(s fmt.State, verb rune) {
 		//Here we use '%_' to print the object's type
 		if verb == '_' {
 			format = append(format, byte('T'))
-		} else if verb == 'd' { //%d
-			//如果代码中使用到%d的形式，由于我们的数字对象中
+		} else if _, ok := ft.Obj.(*Number); ok && verb == 'd' { //%d
+			//如果代码中使用到%d的形式，由于浮点数对象中
 			//存储的是浮点类型，所以会出现类似下面的内容：
 			//  x=%!d(float64=12)
 			//因此，这里我们把%d转换成%g
@@ -831,6 +1160,45 @@ func (ft *Formatter) Format(s fmt.State, verb rune) {
 		fmt.Fprintf(s, formatStr, obj.Bool)
 	case *Number:
 		fmt.Fprintf(s, formatStr, obj.Value)
+	case *Integer:
+		switch verb {
+		case 'e', 'E', 'f', 'F', 'g', 'G':
+			fmt.Fprintf(s, formatStr, float64(obj.Value))
+		case 's', 'q':
+			fmt.Fprintf(s, formatStr, obj.Inspect())
+		default:
+			fmt.Fprintf(s, formatStr, obj.Value)
+		}
+	case *BigInt:
+		switch verb {
+		case 'e', 'E', 'f', 'F', 'g', 'G':
+			fmt.Fprintf(s, formatStr, new(big.Float).SetInt(obj.Value))
+		default:
+			fmt.Fprintf(s, formatStr, obj.Value)
+		}
+	case *Decimal:
+		switch verb {
+		case 'f', 'F':
+			//format the decimal ourselves, so no precision is lost.
+			if prec, ok = s.Precision(); !ok {
+				prec = 6
+			}
+			str := obj.Round(int32(prec)).Inspect()
+			if s.Flag('+') && obj.Unscaled.Sign() >= 0 {
+				str = "+" + str
+			}
+			if s.Flag('-') {
+				fmt.Fprintf(s, "%-*s", width, str)
+			} else {
+				fmt.Fprintf(s, "%*s", width, str)
+			}
+		case 'e', 'E', 'g', 'G':
+			fmt.Fprintf(s, formatStr, obj.Float64())
+		case 'd':
+			fmt.Fprintf(s, formatStr, obj.Trunc())
+		default:
+			fmt.Fprintf(s, formatStr, obj.Inspect())
+		}
 	case *String:
 		fmt.Fprintf(s, formatStr, obj.String)
 	default:
@@ -913,39 +1281,371 @@ func (re *RegEx) split(line string, args ...Object) Object {
 	return a
 }
 
+//StructType is created by the 'struct' statement, e.g. struct Dog : Animal { ... }
+type StructType struct {
+	Name       string
+	Parent     *StructType  //nil if the struct has no parent
+	Interfaces []*Interface //interfaces declared by the struct itself
+	Scope      *Scope       //scope where the struct is declared
+	Fields     []ast.Statement
+	Methods    map[string]*ast.FunctionLiteral //methods declared by the struct itself
+
+	cache    map[string]*structMethod //resolved methods, including the inherited ones
+	conforms map[*Interface]bool      //results of the conformance checks
+}
+
+type structMethod struct {
+	owner *StructType //the struct which declares the method
+	fn    *ast.FunctionLiteral
+}
+
+func (t *StructType) Type() ObjectType { return STRUCT_TYPE_OBJ }
+func (t *StructType) Inspect() string {
+	if t.Parent != nil {
+		return "struct " + t.Name + " : " + t.Parent.Name
+	}
+	return "struct " + t.Name
+}
+
+func (t *StructType) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
+	return newError(line, ERR_NOMETHOD, method, t.Type())
+}
+
+//lookup finds the method through the parent chain, the result(even if not found) is cached.
+func (t *StructType) lookup(name string) *structMethod {
+	if m, ok := t.cache[name]; ok {
+		return m
+	}
+
+	var m *structMethod
+	for c := t; c != nil; c = c.Parent {
+		if fn, ok := c.Methods[name]; ok {
+			m = &structMethod{owner: c, fn: fn}
+			break
+		}
+	}
+	t.cache[name] = m
+	return m
+}
+
+//isSubtypeOf reports whether 't' is 'other' or derives from it.
+func (t *StructType) isSubtypeOf(other *StructType) bool {
+	for c := t; c != nil; c = c.Parent {
+		if c == other {
+			return true
+		}
+	}
+	return false
+}
+
+//implements reports whether the struct has all the methods of 'iface', the result is cached.
+//If not, the returned error describes the first mismatched method.
+func (t *StructType) implements(line string, iface *Interface) (bool, Object) {
+	if ok, checked := t.conforms[iface]; checked {
+		return ok, nil
+	}
+
+	var err Object
+	for _, im := range iface.Methods {
+		m := t.lookup(im.Name)
+		if m == nil {
+			err = newError(line, ERR_NOTIMPLEMENTED, t.Name, iface.Name, im.String(), strings.TrimSpace(im.Pos().String()))
+			break
+		}
+		if !acceptsArgs(m.fn, im) {
+			err = newError(line, ERR_IMPLSIGNATURE, t.Name, iface.Name, im.Name, strings.TrimSpace(m.fn.Pos().String()), im.String())
+			break
+		}
+	}
+	t.conforms[iface] = err == nil
+	return err == nil, err
+}
+
+//acceptsArgs reports whether 'fn' can be called the way the interface method 'im' is declared.
+func acceptsArgs(fn *ast.FunctionLiteral, im *ast.InterfaceMethod) bool {
+	fixed := len(fn.Parameters)
+	if fn.Variadic {
+		fixed--
+	}
+	required := fixed - len(fn.Values)
+
+	n := len(im.Parameters)
+	if im.Variadic {
+		n--
+	}
+	return n >= required && (fn.Variadic || (!im.Variadic && n <= fixed))
+}
+
+//Interface is created by the 'interface' statement, e.g. interface Comparable { fn Compare(other) }
+type Interface struct {
+	Name    string
+	Methods []*ast.InterfaceMethod
+}
+
+func (i *Interface) Type() ObjectType { return INTERFACE_OBJ }
+func (i *Interface) Inspect() string {
+	methods := []string{}
+	for _, m := range i.Methods {
+		methods = append(methods, m.String())
+	}
+	return "interface " + i.Name + " { " + strings.Join(methods, "; ") + " }"
+}
+
+func (i *Interface) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
+	switch method {
+	case "methods":
+		if len(args) != 0 {
+			return newError(line, ERR_ARGUMENT, 0, len(args))
+		}
+		arr := &Array{}
+		for _, m := range i.Methods {
+			arr.Members = append(arr.Members, NewString(m.Name))
+		}
+		return arr
+	}
+	return newError(line, ERR_NOMETHOD, method, i.Type())
+}
+
 type Struct struct {
-	Scope *Scope //struct's scope
+	Class  *StructType //the struct which creates the object
+	Scope  *Scope      //struct's scope, holds the fields
+	Frozen bool        //set by 'freeze()'
+
+	constructing bool //true while the constructor runs, the read-only fields can be set
 }
 
+//Inspect calls '__str__' if the struct has one, otherwise it shows the fields in declaration order,
+//e.g. Point{x: 1, y: 2}
 func (s *Struct) Inspect() string {
+	if s.hasMethod("__str__") {
+		r := s.callMethod("", s.Scope, "__str__", nil, nil)
+		if str, ok := r.(*String); ok {
+			return str.String
+		}
+		if r != s {
+			return r.Inspect()
+		}
+	}
+
 	var out bytes.Buffer
-	out.WriteString("( ")
-	for k, v := range s.Scope.store {
-		out.WriteString(k)
-		out.WriteString("->")
-		out.WriteString(v.Inspect())
-		out.WriteString(" ")
+	fields := []string{}
+	for _, k := range s.Scope.keys {
+		v := s.Scope.store[k]
+		if v.Type() == STRING_OBJ {
+			fields = append(fields, k+": \""+v.Inspect()+"\"")
+		} else {
+			fields = append(fields, k+": "+v.Inspect())
+		}
 	}
-	out.WriteString(" )")
 
+	out.WriteString(s.Class.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
 	return out.String()
 }
 
+//hashKey uses the result of '__hash__' if the struct has one, otherwise the object's identity.
+//It fails if the struct only defines '__eq__', because equal objects must have the same hash key.
+func (s *Struct) hashKey(line string) (HashKey, Object) {
+	h := fnv.New64a()
+	h.Write([]byte(s.Class.Name))
+
+	var value uint64
+	if s.hasMethod("__hash__") {
+		r := s.callMethod(line, s.Scope, "__hash__", nil, nil)
+		if isError(r) {
+			return HashKey{}, r
+		}
+		hk, err := hashKeyOf(line, r)
+		if err != nil {
+			return hk, newError(line, ERR_HASHRESULT, s.Class.Name, r.Type())
+		}
+		value = hk.Value
+	} else if s.hasMethod("__eq__") {
+		return HashKey{}, newError(line, ERR_UNHASHABLE, s.Class.Name)
+	} else {
+		value = uint64(reflect.ValueOf(s).Pointer())
+	}
+
+	var buf [8]byte
+	for i := range buf {
+		buf[i] = byte(value >> (8 * i))
+	}
+	h.Write(buf[:])
+	return HashKey{Type: s.Type(), Value: h.Sum64()}, nil
+}
+
 func (s *Struct) Type() ObjectType { return STRUCT_OBJ }
 func (s *Struct) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
-	var fn2 Object
-	var fn *Function
-	var ok bool
+	return s.callMethod(line, scope, method, args, nil)
+}
+
+//get returns the field or the method named 'name'.
+func (s *Struct) get(name string) (Object, bool) {
+	if v, ok := s.Scope.store[name]; ok {
+		return v, true
+	}
+	if m := s.Class.lookup(name); m != nil {
+		return &Function{Literal: m.fn, Scope: s.Scope}, true
+	}
+	return s.Scope.Get(name)
+}
 
-	if fn2, ok = s.Scope.Get(method); !ok {
+//hasMethod reports whether the struct has the method(including the inherited ones), e.g. '__add__'.
+func (s *Struct) hasMethod(name string) bool {
+	return s.Class.lookup(name) != nil
+}
+
+//callMethod calls the struct's method with positional and keyword arguments.
+func (s *Struct) callMethod(line string, scope *Scope, method string, args []Object, named *namedArgs) Object {
+	if fn, ok := s.Scope.store[method].(*Function); ok { //a function stored in a field
+		return s.invoke(line, fn, nil, args, named)
+	}
+	return s.callMethodOf(line, s.Class, method, args, named)
+}
+
+//callMethodOf calls the method which is resolved from 't', it's used by 'super.method()' too.
+func (s *Struct) callMethodOf(line string, t *StructType, method string, args []Object, named *namedArgs) Object {
+	m := t.lookup(method)
+	if m == nil {
 		return newError(line, ERR_NOMETHOD, method, s.Type())
 	}
+	return s.invoke(line, &Function{Literal: m.fn, Scope: s.Scope}, m.owner.Parent, args, named)
+}
 
-	fn = fn2.(*Function)
-	extendedScope := extendFunctionScope(fn, args)
+func (s *Struct) invoke(line string, fn *Function, parent *StructType, args []Object, named *namedArgs) Object {
+	extendedScope, err := extendFunctionScope(line, fn, args, named)
+	if err != nil {
+		return err
+	}
 	extendedScope.Set("self", s)
+	if parent != nil {
+		extendedScope.Set("super", &Super{Self: s, Class: parent})
+	}
 	obj := Eval(fn.Literal.Body, extendedScope)
-	return unwrapReturnValue(obj)
+	return runDeferred(extendedScope, unwrapReturnValue(obj))
+}
+
+//Super is bound to 'super' in the methods of a derived struct, e.g. super.init(name)
+type Super struct {
+	Self  *Struct
+	Class *StructType //parent of the struct which declares the running method
+}
+
+func (s *Super) Type() ObjectType { return SUPER_OBJ }
+func (s *Super) Inspect() string  { return "super(" + s.Class.Name + ")" }
+func (s *Super) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
+	return s.Self.callMethodOf(line, s.Class, method, args, nil)
+}
+
+//Enum is created by the 'enum' statement, e.g. enum Color { Red, Green, Blue = 10 }
+type Enum struct {
+	Name    string
+	Members []*EnumMember //in declaration order
+}
+
+func (e *Enum) iter() bool       { return true }
+func (e *Enum) Type() ObjectType { return ENUM_OBJ }
+func (e *Enum) Inspect() string {
+	var out bytes.Buffer
+	members := []string{}
+	for _, m := range e.Members {
+		members = append(members, fmt.Sprintf("%s = %d", m.Name, m.Value))
+	}
+
+	out.WriteString("enum ")
+	out.WriteString(e.Name)
+	if len(members) == 0 {
+		out.WriteString(" {}")
+		return out.String()
+	}
+	out.WriteString(" { ")
+	out.WriteString(strings.Join(members, ", "))
+	out.WriteString(" }")
+	return out.String()
+}
+
+func (e *Enum) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
+	switch method {
+	case "members":
+		return e.members(line, args...)
+	case "from":
+		return e.from(line, args...)
+	}
+	return newError(line, ERR_NOMETHOD, method, e.Type())
+}
+
+//member returns the member named 'name', e.g. Color.Red
+func (e *Enum) member(line string, name string) Object {
+	for _, m := range e.Members {
+		if m.Name == name {
+			return m
+		}
+	}
+	return newError(line, ERR_ENUMMEMBER, name, e.Name)
+}
+
+func (e *Enum) members(line string, args ...Object) Object {
+	if len(args) != 0 {
+		return newError(line, ERR_ARGUMENT, "0", len(args))
+	}
+
+	arr := &Array{}
+	for _, m := range e.Members {
+		arr.Members = append(arr.Members, m)
+	}
+	return arr
+}
+
+//from returns the member which has the value, nil if not found, e.g. Color.from(10)
+func (e *Enum) from(line string, args ...Object) Object {
+	if len(args) != 1 {
+		return newError(line, ERR_ARGUMENT, "1", len(args))
+	}
+
+	value, ok := toInt64(args[0])
+	if !ok {
+		return newError(line, ERR_PARAMTYPE, "first", "from", "*Integer", args[0].Type())
+	}
+	for _, m := range e.Members {
+		if m.Value == value {
+			return m
+		}
+	}
+	return NIL
+}
+
+type EnumMember struct {
+	Enum    *Enum
+	Name    string
+	Value   int64
+	Ordinal int //position in the enum declaration
+}
+
+func (m *EnumMember) Type() ObjectType { return ENUM_MEMBER_OBJ }
+func (m *EnumMember) Inspect() string  { return m.Enum.Name + "." + m.Name }
+func (m *EnumMember) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(m.Inspect()))
+	return HashKey{Type: m.Type(), Value: h.Sum64()}
+}
+
+func (m *EnumMember) CallMethod(line string, scope *Scope, method string, args ...Object) Object {
+	return newError(line, ERR_NOMETHOD, method, m.Type())
+}
+
+//field returns the member's name, value or ordinal, e.g. Color.Red.name
+func (m *EnumMember) field(line string, name string) Object {
+	switch name {
+	case "name":
+		return NewString(m.Name)
+	case "value":
+		return NewInteger(m.Value)
+	case "ordinal":
+		return NewInteger(int64(m.Ordinal))
+	}
+	return newError(line, ERR_NOMETHOD, name, m.Type())
 }
 
 type Throw struct {
@@ -100,12 +100,12 @@ func (o *Os) mkdir(line string, args ...Object) Object {
 		return newError(line, ERR_PARAMTYPE, "first", "mkdir", "*String", args[0].Type())
 	}
 
-	perm, ok := args[1].(*Number)
+	perm, ok := toInt64(args[1])
 	if !ok {
-		return newError(line, ERR_PARAMTYPE, "second", "mkdir", "*Number", args[1].Type())
+		return newError(line, ERR_PARAMTYPE, "second", "mkdir", "*Integer", args[1].Type())
 	}
 
-	err := os.Mkdir(name.String, os.FileMode(int64(perm.Value)))
+	err := os.Mkdir(name.String, os.FileMode(perm))
 	if err != nil {
 		return FALSE
 	}
@@ -122,12 +122,12 @@ func (o *Os) exit(line string, args ...Object) Object {
 		return NIL
 	}
 
-	code, ok := args[0].(*Number)
+	code, ok := toInt64(args[0])
 	if !ok {
-		return newError(line, ERR_PARAMTYPE, "first", "exit", "*Number", args[0].Type())
+		return newError(line, ERR_PARAMTYPE, "first", "exit", "*Integer", args[0].Type())
 	}
 
-	os.Exit(int(code.Value))
+	os.Exit(int(code))
 
 	return NIL
 }
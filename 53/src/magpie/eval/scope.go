@@ -3,13 +3,12 @@ package eval
 import (
 	"fmt"
 	"io"
-	"magpie/ast"
 	"unicode"
 )
 
 func NewScope(p *Scope, w io.Writer) *Scope {
 	s := make(map[string]Object)
-	ss := make(map[string]*ast.StructStatement)
+	ss := make(map[string]*StructType)
 	ret := &Scope{store: s, parentScope: p, structStore: ss}
 	if p == nil {
 		ret.Writer = w
@@ -25,14 +24,19 @@ type Scope struct {
 	parentScope *Scope
 	Writer      io.Writer
 
-	structStore map[string]*ast.StructStatement
+	structStore map[string]*StructType
+	namedArgs   *namedArgs      //keyword arguments forwarded by '$_'
+	deferred    []*deferredCall //calls queued by 'defer', only used by function frames
+	readonly    map[string]bool //names which can not be reassigned, e.g. constants
+	ordered     bool            //the order of the names is kept in 'keys', only used by struct objects
+	keys        []string
 }
 
 //Get all exported to 'anotherScope'
 func (s *Scope) GetAllExported(anotherScope *Scope) {
 	for key, value := range s.store {
 		if unicode.IsUpper(rune(key[0])) { //only upppercase functions/variables are exported
-			anotherScope.Set(key, value)
+			anotherScope.SetConst(key, value) //imported names are read-only
 		}
 	}
 
@@ -73,16 +77,82 @@ func (s *Scope) DebugPrint(indent string) {
 
 }
 
+//getAllArgs returns the arguments('$_') of the innermost function.
+func (s *Scope) getAllArgs() ([]Object, *namedArgs) {
+	for ; s != nil; s = s.parentScope {
+		if arr, ok := s.store[ALL_ARGS]; ok {
+			return append([]Object{}, arr.(*Array).Members...), s.namedArgs
+		}
+	}
+	return nil, nil
+}
+
+//frame returns the scope of the innermost function call, nil if not inside a function.
+func (s *Scope) frame() *Scope {
+	for ; s != nil; s = s.parentScope {
+		if _, ok := s.store[ALL_ARGS]; ok {
+			return s
+		}
+	}
+	return nil
+}
+
 func (s *Scope) Set(name string, val Object) Object {
+	if name == DEFER_RESULT { //'$result' belongs to the frame which runs the deferred calls
+		for f := s; f != nil; f = f.parentScope {
+			if _, ok := f.store[name]; ok {
+				f.store[name] = val
+				return val
+			}
+		}
+	}
+	s.addKey(name)
 	s.store[name] = val
 	return val
 }
 
+//addKey remembers the order of the new names if the scope is ordered.
+func (s *Scope) addKey(name string) {
+	if s.ordered {
+		if _, ok := s.store[name]; !ok {
+			s.keys = append(s.keys, name)
+		}
+	}
+}
+
+//SetConst sets a read-only name.
+func (s *Scope) SetConst(name string, val Object) Object {
+	if s.readonly == nil {
+		s.readonly = make(map[string]bool)
+	}
+	s.readonly[name] = true
+	s.addKey(name)
+	s.store[name] = val
+	return val
+}
+
+//IsConst reports whether 'name' resolves to a read-only name.
+func (s *Scope) IsConst(name string) bool {
+	for ; s != nil; s = s.parentScope {
+		if _, ok := s.store[name]; ok {
+			return s.readonly[name]
+		}
+	}
+	return false
+}
+
 func (s *Scope) Del(name string) {
 	delete(s.store, name)
+	delete(s.readonly, name)
+	for i, k := range s.keys {
+		if k == name {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			break
+		}
+	}
 }
 
-func (s *Scope) GetStruct(name string) (*ast.StructStatement, bool) {
+func (s *Scope) GetStruct(name string) (*StructType, bool) {
 	obj, ok := s.structStore[name]
 	if !ok && s.parentScope != nil {
 		obj, ok = s.parentScope.GetStruct(name)
@@ -90,9 +160,9 @@ func (s *Scope) GetStruct(name string) (*ast.StructStatement, bool) {
 	return obj, ok
 }
 
-func (s *Scope) SetStruct(structStmt *ast.StructStatement) *ast.StructStatement {
-	s.structStore[structStmt.Name] = structStmt
-	return structStmt
+func (s *Scope) SetStruct(t *StructType) *StructType {
+	s.structStore[t.Name] = t
+	return t
 }
 
 var GlobalScopes map[string]Object = make(map[string]Object)
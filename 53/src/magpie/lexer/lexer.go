@@ -7,9 +7,13 @@ import (
 	"magpie/token"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
-var prevToken token.Token
+//EscapeMark marks the escaped characters in the literal of a string token, so the
+//parser can tell a '\$'(marked) from a '$' which starts an interpolation.
+//A NUL character in the string is marked too, so the marker is unambiguous.
+const EscapeMark = '\x00'
 
 // Lexer
 type Lexer struct {
@@ -21,6 +25,8 @@ type Lexer struct {
 
 	line int
 	col  int
+
+	prevToken token.Token //used to decide whether '/' is a division or a regular expression
 }
 
 func NewFileLexer(filename string) (*Lexer, error) {
@@ -52,6 +58,16 @@ func NewLexer(input string) *Lexer {
 	return l
 }
 
+//NewExprLexer creates a lexer for an expression embedded in a string literal,
+//e.g. the 'a + b' in "sum = ${a + b}". 'pos' is the position of the string literal.
+func NewExprLexer(input string, pos token.Position) *Lexer {
+	l := NewLexer(input)
+	l.Filename = pos.Filename
+	l.line = pos.Line
+	l.col += pos.Col
+	return l
+}
+
 func (l *Lexer) readNext() {
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
@@ -76,6 +92,14 @@ func (l *Lexer) peek() rune {
 	return l.input[l.readPosition]
 }
 
+//peek the rune after the next rune
+func (l *Lexer) peek2() rune {
+	if l.readPosition+1 >= len(l.input) {
+		return 0
+	}
+	return l.input[l.readPosition+1]
+}
+
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 	l.skipWhitespace()
@@ -132,10 +156,12 @@ func (l *Lexer) NextToken() token.Token {
 		}
 
 		// '/'通常表示除法，但是也可能是一个正则表达式
-		if prevToken.Type == token.TOKEN_RPAREN || // (a+c) / b
-			prevToken.Type == token.TOKEN_RBRACKET || // a[3] / b
-			prevToken.Type == token.TOKEN_IDENTIFIER || // a / b
-			prevToken.Type == token.TOKEN_NUMBER { // 3 / b,  3.5 / b
+		if l.prevToken.Type == token.TOKEN_RPAREN || // (a+c) / b
+			l.prevToken.Type == token.TOKEN_RBRACKET || // a[3] / b
+			l.prevToken.Type == token.TOKEN_IDENTIFIER || // a / b
+			l.prevToken.Type == token.TOKEN_NUMBER || // 3 / b,  3.5 / b
+			l.prevToken.Type == token.TOKEN_BIGINT || // 3n / b
+			l.prevToken.Type == token.TOKEN_DECIMAL { // 3.5d / b
 			if l.peek() == '=' {
 				tok = token.Token{Type: token.TOKEN_SLASH_A, Literal: string(l.ch) + string(l.peek())}
 				l.readNext()
@@ -189,6 +215,14 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peek() == '=' {
 			tok = token.Token{Type: token.TOKEN_GE, Literal: string(l.ch) + string(l.peek())}
 			l.readNext()
+		} else if l.peek() == '>' {
+			l.readNext()
+			if l.peek() == '=' {
+				tok = token.Token{Type: token.TOKEN_SHIFT_R_A, Literal: ">>="}
+				l.readNext()
+			} else {
+				tok = token.Token{Type: token.TOKEN_SHIFT_R, Literal: ">>"}
+			}
 		} else {
 			tok = newToken(token.TOKEN_GT, l.ch)
 		}
@@ -196,6 +230,14 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peek() == '=' {
 			tok = token.Token{Type: token.TOKEN_LE, Literal: string(l.ch) + string(l.peek())}
 			l.readNext()
+		} else if l.peek() == '<' {
+			l.readNext()
+			if l.peek() == '=' {
+				tok = token.Token{Type: token.TOKEN_SHIFT_L_A, Literal: "<<="}
+				l.readNext()
+			} else {
+				tok = token.Token{Type: token.TOKEN_SHIFT_L, Literal: "<<"}
+			}
 		} else {
 			tok = newToken(token.TOKEN_LT, l.ch)
 		}
@@ -213,6 +255,19 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.TOKEN_SEMICOLON, l.ch)
 	case ':':
 		tok = newToken(token.TOKEN_COLON, l.ch)
+	case '?':
+		if l.peek() == '?' {
+			tok = token.Token{Type: token.TOKEN_NULLISH, Literal: string(l.ch) + string(l.peek())}
+			l.readNext()
+		} else if l.peek() == '.' {
+			tok = token.Token{Type: token.TOKEN_OPTDOT, Literal: string(l.ch) + string(l.peek())}
+			l.readNext()
+		} else if l.peek() == '[' { //Note: 'cond ?[1] : [2]' must be written as 'cond ? [1] : [2]'
+			tok = token.Token{Type: token.TOKEN_OPTINDEX, Literal: string(l.ch) + string(l.peek())}
+			l.readNext()
+		} else {
+			tok = newToken(token.TOKEN_QUESTION, l.ch)
+		}
 	case ',':
 		tok = newToken(token.TOKEN_COMMA, l.ch)
 	case '.':
@@ -235,6 +290,11 @@ func (l *Lexer) NextToken() token.Token {
 		if l.peek() == '&' {
 			tok = token.Token{Type: token.TOKEN_AND, Literal: string(l.ch) + string(l.peek())}
 			l.readNext()
+		} else if l.peek() == '=' {
+			tok = token.Token{Type: token.TOKEN_BITAND_A, Literal: string(l.ch) + string(l.peek())}
+			l.readNext()
+		} else {
+			tok = newToken(token.TOKEN_BITAND, l.ch)
 		}
 	case '|':
 		if l.peek() == '|' {
@@ -243,7 +303,21 @@ func (l *Lexer) NextToken() token.Token {
 		} else if l.peek() == '>' {
 			tok = token.Token{Type: token.TOKEN_PIPE, Literal: string(l.ch) + string(l.peek())}
 			l.readNext()
+		} else if l.peek() == '=' {
+			tok = token.Token{Type: token.TOKEN_BITOR_A, Literal: string(l.ch) + string(l.peek())}
+			l.readNext()
+		} else {
+			tok = newToken(token.TOKEN_BITOR, l.ch)
 		}
+	case '^':
+		if l.peek() == '=' {
+			tok = token.Token{Type: token.TOKEN_BITXOR_A, Literal: string(l.ch) + string(l.peek())}
+			l.readNext()
+		} else {
+			tok = newToken(token.TOKEN_BITXOR, l.ch)
+		}
+	case '~':
+		tok = newToken(token.TOKEN_BITNOT, l.ch)
 	case '#': //comment
 		l.skipComment()
 		return l.NextToken()
@@ -253,22 +327,68 @@ func (l *Lexer) NextToken() token.Token {
 	default:
 		if isDigit(l.ch) {
 			tok.Literal = l.readNumber()
-			tok.Type = token.TOKEN_NUMBER
+			//for 0x/0o/0b literals, a trailing 'd' is a hex digit, not the decimal suffix
+			prefixed := len(tok.Literal) > 1 && tok.Literal[0] == '0' && strings.ContainsRune("xXoObB", rune(tok.Literal[1]))
+			switch tok.Literal[len(tok.Literal)-1] {
+			case 'n':
+				tok.Type = token.TOKEN_BIGINT
+			case 'd':
+				if prefixed {
+					tok.Type = token.TOKEN_NUMBER
+				} else {
+					tok.Type = token.TOKEN_DECIMAL
+				}
+			default:
+				tok.Type = token.TOKEN_NUMBER
+			}
 			tok.Pos = pos
-			prevToken = tok
+			l.prevToken = tok
+			return tok
+		} else if l.ch == 'r' && l.peek() == '"' { //raw string: r"...", r"""..."""
+			l.readNext()
+			var s string
+			var err error
+			if l.peek() == '"' && l.peek2() == '"' {
+				s, err = l.readTripleQuotedString()
+			} else {
+				s, err = l.readRawString()
+			}
+			if err != nil {
+				tok.Type = token.TOKEN_ILLEGAL
+				tok.Pos = pos
+				tok.Literal = err.Error()
+				return tok
+			}
+			tok.Type = token.TOKEN_RAW_STRING
+			tok.Pos = pos
+			tok.Literal = s
+			l.prevToken = tok
 			return tok
 		} else if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Pos = pos
 			tok.Type = token.LookupIdent(tok.Literal)
-			prevToken = tok
+			l.prevToken = tok
 			return tok
+		} else if l.ch == '"' && l.peek() == '"' && l.peek2() == '"' { //multi-line string: """..."""
+			if s, err := l.readTripleQuotedString(); err == nil {
+				tok.Type = token.TOKEN_RAW_STRING
+				tok.Pos = pos
+				tok.Literal = dedent(s)
+				l.prevToken = tok
+				return tok
+			} else {
+				tok.Type = token.TOKEN_ILLEGAL
+				tok.Pos = pos
+				tok.Literal = err.Error()
+				return tok
+			}
 		} else if l.ch == 34 { //double quotes
 			if s, err := l.readString(l.ch); err == nil {
 				tok.Type = token.TOKEN_STRING
 				tok.Pos = pos
 				tok.Literal = s
-				prevToken = tok
+				l.prevToken = tok
 				return tok
 			} else {
 				tok.Type = token.TOKEN_ILLEGAL
@@ -294,7 +414,7 @@ func (l *Lexer) NextToken() token.Token {
 
 	tok.Pos = pos
 	l.readNext()
-	prevToken = tok
+	l.prevToken = tok
 	return tok
 }
 
@@ -349,7 +469,25 @@ func (l *Lexer) readNumber() string {
 	ret = append(ret, ch)
 	l.readNext()
 
-	for isDigit(l.ch) || l.ch == '.' {
+	//0xFF(hex), 0o755(octal), 0b1010(binary)
+	if ch == '0' && strings.ContainsRune("xXoObB", l.ch) {
+		ret = append(ret, l.ch)
+		l.readNext()
+		for isHexDigit(l.ch) || l.ch == '_' {
+			ret = append(ret, l.ch)
+			l.readNext()
+		}
+
+		//'d' is a hex digit, so only the bigint suffix is allowed here, e.g. 0xFFn
+		if l.ch == 'n' && !isLetter(l.peek()) && !isDigit(l.peek()) {
+			ret = append(ret, l.ch)
+			l.readNext()
+		}
+		return string(ret)
+	}
+
+	//'_' is used as a digit separator, e.g. 1_000_000
+	for isDigit(l.ch) || l.ch == '_' || l.ch == '.' {
 		if l.ch == '.' {
 			if !isDigit(l.peek()) { //should be a method calling, e.g. 10.2.floor()
 				return string(ret)
@@ -359,6 +497,24 @@ func (l *Lexer) readNumber() string {
 		l.readNext()
 	}
 
+	//exponent, e.g. 1.5e-3, 6.02E23
+	if (l.ch == 'e' || l.ch == 'E') &&
+		(isDigit(l.peek()) || ((l.peek() == '+' || l.peek() == '-') && isDigit(l.peek2()))) {
+		ret = append(ret, l.ch, l.peek())
+		l.readNext()
+		l.readNext()
+		for isDigit(l.ch) || l.ch == '_' {
+			ret = append(ret, l.ch)
+			l.readNext()
+		}
+	}
+
+	//suffix: 'n' for bigint(e.g. 123n), 'd' for decimal(e.g. 12.30d)
+	if (l.ch == 'n' || l.ch == 'd') && !isLetter(l.peek()) && !isDigit(l.peek()) {
+		ret = append(ret, l.ch)
+		l.readNext()
+	}
+
 	return string(ret)
 }
 
@@ -385,25 +541,31 @@ eos:
 			break eos //eos:end of string
 		case '\\':
 			l.readNext()
-			switch l.ch {
-			case 'b':
-				ret = append(ret, '\b')
+			if l.ch == '$' { //'\$' is marked, so the interpolation knows it's escaped
+				ret = append(ret, EscapeMark, '$')
 				continue
-			case 'f':
-				ret = append(ret, '\f')
-				continue
-			case 'r':
-				ret = append(ret, '\r')
-				continue
-			case 'n':
-				ret = append(ret, '\n')
-				continue
-			case 't':
-				ret = append(ret, '\t')
+			}
+			chars, err := l.readEscape()
+			if err != nil {
+				return "", err
+			}
+			for _, c := range chars {
+				if c == EscapeMark {
+					ret = append(ret, EscapeMark)
+				}
+				ret = append(ret, c)
+			}
+			continue
+		case '$':
+			//"${expr}" is kept verbatim for the parser, so the expression may contain strings: "${h["k"]}"
+			if end := l.interpolationEnd(); end != -1 {
+				ret = append(ret, l.input[l.position:end+1]...)
+				for l.position < end {
+					l.readNext()
+				}
 				continue
 			}
 			ret = append(ret, l.ch)
-			continue
 		default:
 			ret = append(ret, l.ch)
 		}
@@ -412,6 +574,180 @@ eos:
 	return string(ret), nil
 }
 
+//interpolationEnd returns the offset of the '}' which closes the "${" at the
+//current position, or -1 if there is none on the current line.
+func (l *Lexer) interpolationEnd() int {
+	if l.peek() != '{' {
+		return -1
+	}
+
+	depth := 0
+	for i := l.readPosition; i < len(l.input); i++ {
+		switch l.input[i] {
+		case '\n':
+			return -1
+		case '"':
+			for i++; i < len(l.input) && l.input[i] != '"'; i++ {
+				if l.input[i] == '\n' {
+					return -1
+				}
+				if l.input[i] == '\\' {
+					i++
+				}
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+//readEscape reads the escape sequence after a '\\', when it returns, the
+//current character is the last character of the escape sequence.
+func (l *Lexer) readEscape() ([]rune, error) {
+	switch l.ch {
+	case 'a':
+		return []rune{'\a'}, nil
+	case 'b':
+		return []rune{'\b'}, nil
+	case 'f':
+		return []rune{'\f'}, nil
+	case 'n':
+		return []rune{'\n'}, nil
+	case 'r':
+		return []rune{'\r'}, nil
+	case 't':
+		return []rune{'\t'}, nil
+	case 'v':
+		return []rune{'\v'}, nil
+	case '\\', '"', '\'', '$':
+		return []rune{l.ch}, nil
+	case 'x': //\xHH
+		return l.readHexEscape(2)
+	case 'u': //\uXXXX
+		return l.readHexEscape(4)
+	case 'U': //\UXXXXXXXX
+		return l.readHexEscape(8)
+	case '0', '1', '2', '3', '4', '5', '6', '7': //octal, e.g. \0, \033, \177
+		value := l.ch - '0'
+		for i := 0; i < 2 && '0' <= l.peek() && l.peek() <= '7'; i++ {
+			l.readNext()
+			value = value*8 + (l.ch - '0')
+		}
+		if value > 255 {
+			return nil, fmt.Errorf("octal escape value %d > 255", value)
+		}
+		return []rune{value}, nil
+	case 0:
+		return nil, errors.New("unexpected EOF")
+	}
+	return nil, fmt.Errorf("unknown escape sequence: \\%c", l.ch)
+}
+
+//readHexEscape reads exactly 'n' hex digits, and returns the unicode character
+func (l *Lexer) readHexEscape(n int) ([]rune, error) {
+	escape := l.ch
+	var value uint64
+	for i := 0; i < n; i++ {
+		l.readNext()
+		if !isHexDigit(l.ch) {
+			return nil, fmt.Errorf("invalid escape sequence: \\%c requires %d hex digits", escape, n)
+		}
+		value = value*16 + uint64(hexValue(l.ch))
+	}
+	if value > utf8.MaxRune || !utf8.ValidRune(rune(value)) {
+		return nil, fmt.Errorf("invalid unicode code point: \\%c%0*X", escape, n, value)
+	}
+	return []rune{rune(value)}, nil
+}
+
+//r"...", no escape processing
+func (l *Lexer) readRawString() (string, error) {
+	var ret []rune
+	for {
+		l.readNext()
+		switch l.ch {
+		case '\n':
+			return "", errors.New("unexpected EOL")
+		case 0:
+			return "", errors.New("unexpected EOF")
+		case '"':
+			l.readNext()
+			return string(ret), nil
+		default:
+			ret = append(ret, l.ch)
+		}
+	}
+}
+
+//"""...""", may span multiple lines, no escape processing
+func (l *Lexer) readTripleQuotedString() (string, error) {
+	var ret []rune
+
+	//skip the opening quotes
+	l.readNext()
+	l.readNext()
+	for {
+		l.readNext()
+		switch {
+		case l.ch == 0:
+			return "", errors.New("unexpected EOF")
+		case l.ch == '"' && l.peek() == '"' && l.peek2() == '"':
+			l.readNext()
+			l.readNext()
+			l.readNext()
+			return string(ret), nil
+		default:
+			ret = append(ret, l.ch)
+		}
+	}
+}
+
+//dedent strips the common indentation of a multi-line string:
+//  1. the line break right after the opening quotes is removed
+//  2. the last line is removed if it is blank(i.e. the closing quotes are on their own line)
+//  3. the common leading whitespace of the non-blank lines is removed
+func dedent(s string) string {
+	if strings.HasPrefix(s, "\r\n") {
+		s = s[2:]
+	} else if strings.HasPrefix(s, "\n") {
+		s = s[1:]
+	}
+
+	lines := strings.Split(s, "\n")
+	if len(lines) > 1 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == -1 || n < indent {
+			indent = n
+		}
+	}
+
+	for i, line := range lines {
+		if indent <= 0 { //no common indentation, keep the lines unchanged
+			break
+		}
+		if len(line) >= indent {
+			lines[i] = line[indent:]
+		} else { //a blank line which is shorter than the indentation
+			lines[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (l *Lexer) readCommand(r rune) (string, error) {
 	var ret []rune
 eoc:
@@ -498,6 +834,20 @@ func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func hexValue(ch rune) rune {
+	switch {
+	case 'a' <= ch && ch <= 'f':
+		return ch - 'a' + 10
+	case 'A' <= ch && ch <= 'F':
+		return ch - 'A' + 10
+	}
+	return ch - '0'
+}
+
 func isLetter(ch rune) bool {
 	return unicode.IsLetter(ch) || ch == '_' || ch == '$'
 }
@@ -8,8 +8,10 @@ import (
 	"magpie/ast"
 	"magpie/lexer"
 	"magpie/token"
+	"math/big"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode/utf8"
@@ -18,12 +20,18 @@ import (
 const (
 	_ int = iota
 	LOWEST
-	ASSIGN       //=, =>, +=, -=, */, /=, %=
+	ASSIGN       //=, =>, +=, -=, */, /=, %=, &=, |=, ^=, <<=, >>=
+	TERNARY      // ? :
+	NULLISH      // ??
 	RANGE        // ..
 	CONDOR       // ||
 	CONDAND      // &&
 	EQUALS       //==, !=
 	LESSGREATER  //<, <=, >, >=, |>
+	BITOR        // |
+	BITXOR       // ^
+	BITAND       // &
+	SHIFTS       //<<, >>
 	SUM          //+, -
 	PRODUCT      //*, /, %, **
 	REGEXP_MATCH // !~, ~=
@@ -39,8 +47,15 @@ var precedences = map[token.TokenType]int{
 	token.TOKEN_ASTERISK_A: ASSIGN,
 	token.TOKEN_SLASH_A:    ASSIGN,
 	token.TOKEN_MOD_A:      ASSIGN,
+	token.TOKEN_BITAND_A:   ASSIGN,
+	token.TOKEN_BITOR_A:    ASSIGN,
+	token.TOKEN_BITXOR_A:   ASSIGN,
+	token.TOKEN_SHIFT_L_A:  ASSIGN,
+	token.TOKEN_SHIFT_R_A:  ASSIGN,
 
 	token.TOKEN_FATARROW: ASSIGN,
+	token.TOKEN_QUESTION: TERNARY,
+	token.TOKEN_NULLISH:  NULLISH,
 	token.TOKEN_OR:       CONDOR,
 	token.TOKEN_AND:      CONDAND,
 
@@ -54,6 +69,12 @@ var precedences = map[token.TokenType]int{
 	token.TOKEN_IN:   LESSGREATER,
 	token.TOKEN_PIPE: LESSGREATER,
 
+	token.TOKEN_BITOR:   BITOR,
+	token.TOKEN_BITXOR:  BITXOR,
+	token.TOKEN_BITAND:  BITAND,
+	token.TOKEN_SHIFT_L: SHIFTS,
+	token.TOKEN_SHIFT_R: SHIFTS,
+
 	token.TOKEN_PLUS:     SUM,
 	token.TOKEN_MINUS:    SUM,
 	token.TOKEN_MULTIPLY: PRODUCT,
@@ -63,7 +84,9 @@ var precedences = map[token.TokenType]int{
 
 	token.TOKEN_LPAREN:    CALL,
 	token.TOKEN_DOT:       CALL,
+	token.TOKEN_OPTDOT:    CALL,
 	token.TOKEN_LBRACKET:  CALL,
+	token.TOKEN_OPTINDEX:  CALL,
 	token.TOKEN_INCREMENT: INCREMENT,
 	token.TOKEN_DECREMENT: INCREMENT,
 
@@ -105,8 +128,9 @@ type Parser struct {
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 
-	loopDepth        int // current loop depth (0 if not in any loops)
-	fallthroughDepth int //current fallthrough depth (0 if not in switch cases)
+	loopDepth        int  // current loop depth (0 if not in any loops)
+	fallthroughDepth int  //current fallthrough depth (0 if not in switch cases)
+	allArgs          bool //the function being parsed uses '$_'
 
 	Attachments *ember.Attachments
 	importLib   map[string]*ast.Program //for use with imported standard libs
@@ -139,8 +163,11 @@ func (p *Parser) registerAction() {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.TOKEN_ILLEGAL, p.parsePrefixIllegalExpression)
 	p.registerPrefix(token.TOKEN_NUMBER, p.parseNumber)
+	p.registerPrefix(token.TOKEN_BIGINT, p.parseBigInt)
+	p.registerPrefix(token.TOKEN_DECIMAL, p.parseDecimal)
 	p.registerPrefix(token.TOKEN_IDENTIFIER, p.parseIdentifier)
 	p.registerPrefix(token.TOKEN_STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TOKEN_RAW_STRING, p.parseStringLiteral)
 	p.registerPrefix(token.TOKEN_FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.TOKEN_TRUE, p.parseBooleanLiteral)
 	p.registerPrefix(token.TOKEN_FALSE, p.parseBooleanLiteral)
@@ -151,6 +178,7 @@ func (p *Parser) registerAction() {
 	p.registerPrefix(token.TOKEN_PLUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TOKEN_MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TOKEN_BANG, p.parsePrefixExpression)
+	p.registerPrefix(token.TOKEN_BITNOT, p.parsePrefixExpression)
 	p.registerPrefix(token.TOKEN_LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.TOKEN_IF, p.parseIfExpression)
 	p.registerPrefix(token.TOKEN_SWITCH, p.parseSwitchExpression)
@@ -174,6 +202,7 @@ func (p *Parser) registerAction() {
 	p.registerInfix(token.TOKEN_POWER, p.parseInfixExpression)
 	p.registerInfix(token.TOKEN_LPAREN, p.parseCallExpression)
 	p.registerInfix(token.TOKEN_LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.TOKEN_OPTINDEX, p.parseIndexExpression)
 
 	p.registerInfix(token.TOKEN_LT, p.parseInfixExpression)
 	p.registerInfix(token.TOKEN_LE, p.parseInfixExpression)
@@ -186,6 +215,14 @@ func (p *Parser) registerAction() {
 
 	p.registerInfix(token.TOKEN_AND, p.parseInfixExpression)
 	p.registerInfix(token.TOKEN_OR, p.parseInfixExpression)
+	p.registerInfix(token.TOKEN_NULLISH, p.parseInfixExpression)
+	p.registerInfix(token.TOKEN_QUESTION, p.parseTernaryExpression)
+
+	p.registerInfix(token.TOKEN_BITAND, p.parseInfixExpression)
+	p.registerInfix(token.TOKEN_BITOR, p.parseInfixExpression)
+	p.registerInfix(token.TOKEN_BITXOR, p.parseInfixExpression)
+	p.registerInfix(token.TOKEN_SHIFT_L, p.parseInfixExpression)
+	p.registerInfix(token.TOKEN_SHIFT_R, p.parseInfixExpression)
 
 	p.registerInfix(token.TOKEN_MATCH, p.parseInfixExpression)
 	p.registerInfix(token.TOKEN_NOTMATCH, p.parseInfixExpression)
@@ -195,6 +232,7 @@ func (p *Parser) registerAction() {
 	p.registerInfix(token.TOKEN_DECREMENT, p.parsePostfixExpression)
 
 	p.registerInfix(token.TOKEN_DOT, p.parseMethodCallExpression)
+	p.registerInfix(token.TOKEN_OPTDOT, p.parseMethodCallExpression)
 
 	p.registerInfix(token.TOKEN_ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.TOKEN_PLUS_A, p.parseAssignExpression)
@@ -202,6 +240,11 @@ func (p *Parser) registerAction() {
 	p.registerInfix(token.TOKEN_ASTERISK_A, p.parseAssignExpression)
 	p.registerInfix(token.TOKEN_SLASH_A, p.parseAssignExpression)
 	p.registerInfix(token.TOKEN_MOD_A, p.parseAssignExpression)
+	p.registerInfix(token.TOKEN_BITAND_A, p.parseAssignExpression)
+	p.registerInfix(token.TOKEN_BITOR_A, p.parseAssignExpression)
+	p.registerInfix(token.TOKEN_BITXOR_A, p.parseAssignExpression)
+	p.registerInfix(token.TOKEN_SHIFT_L_A, p.parseAssignExpression)
+	p.registerInfix(token.TOKEN_SHIFT_R_A, p.parseAssignExpression)
 
 	p.registerInfix(token.TOKEN_FATARROW, p.parseFatArrow)
 }
@@ -243,14 +286,25 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseImportStatement()
 	case token.TOKEN_LET:
 		return p.parseLetStatement()
+	case token.TOKEN_CONST:
+		return p.parseConstStatement()
 	case token.TOKEN_RETURN:
 		return p.parseReturnStatement()
 	case token.TOKEN_TAIL:
 		return p.parseTailCallStatement()
+	case token.TOKEN_DEFER:
+		return p.parseDeferStatement()
 	case token.TOKEN_LBRACE:
+		if p.isHashPatternAssign() {
+			return p.parseHashPatternAssignStatement()
+		}
 		return p.parseBlockStatement()
 	case token.TOKEN_STRUCT:
 		return p.parseStructStatement()
+	case token.TOKEN_ENUM:
+		return p.parseEnumStatement()
+	case token.TOKEN_INTERFACE:
+		return p.parseInterfaceStatement()
 	case token.TOKEN_TRY:
 		return p.parseTryStatement()
 	case token.TOKEN_THROW:
@@ -378,23 +432,15 @@ func (p *Parser) getImportedStatements(importpath string) (*ast.Program, error)
 
 //let a,b,c = 1,2,3 (with assignment)
 //let a; (without assignment, 'a' is assumed to be 'nil')
+//let [a, b, ...rest], {name} = arr, hash (with destructuring)
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
 	//parse left hand side of the assignment
 	for {
 		p.nextToken()
-		if !p.curTokenIs(token.TOKEN_IDENTIFIER) && p.curToken.Literal != "_" {
-			msg := fmt.Sprintf("Syntax Error:%v- expected token to be identifier|underscore, got %s instead.", p.curToken.Pos, p.curToken.Type)
-			p.errors = append(p.errors, msg)
-			p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
-			return stmt
-		}
-		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		if p.curToken.Literal == "self" {
-			msg := fmt.Sprintf("Syntax Error:%v- 'self' can not be assigned", p.curToken.Pos)
-			p.errors = append(p.errors, msg)
-			p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+		name := p.parsePattern()
+		if name == nil {
 			return nil
 		}
 		stmt.Names = append(stmt.Names, name)
@@ -430,6 +476,222 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+//const NAME = value
+//const (x, y) = point
+func (p *Parser) parseConstStatement() *ast.LetStatement {
+	tok := p.curToken
+	stmt := p.parseLetStatement()
+	if stmt != nil && len(stmt.Values) == 0 {
+		msg := fmt.Sprintf("Syntax Error:%v- missing value in const declaration", tok.Pos)
+		p.errors = append(p.errors, msg)
+		p.errorLines = append(p.errorLines, tok.Pos.Sline())
+		return nil
+	}
+	return stmt
+}
+
+//parsePattern parses a name or a destructuring pattern, the current token
+//should be an identifier, '_', '[', '(' or '{':
+//    [first, second, ...rest]
+//    (x, y)
+//    {name, age: years, "home": {city}, ...others}
+func (p *Parser) parsePattern() ast.Expression {
+	switch p.curToken.Type {
+	case token.TOKEN_LBRACKET:
+		return p.parseArrayPattern(token.TOKEN_RBRACKET)
+	case token.TOKEN_LPAREN:
+		return p.parseArrayPattern(token.TOKEN_RPAREN)
+	case token.TOKEN_LBRACE:
+		return p.parseHashPattern()
+	}
+
+	if !p.curTokenIs(token.TOKEN_IDENTIFIER) && p.curToken.Literal != "_" {
+		msg := fmt.Sprintf("Syntax Error:%v- expected token to be identifier|underscore|pattern, got %s instead.", p.curToken.Pos, p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+		return nil
+	}
+	if p.curToken.Literal == "self" {
+		msg := fmt.Sprintf("Syntax Error:%v- 'self' can not be assigned", p.curToken.Pos)
+		p.errors = append(p.errors, msg)
+		p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+		return nil
+	}
+	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+//[a, b, ...rest] or (a, b, ...rest), the rest must be the last one
+func (p *Parser) parseArrayPattern(end token.TokenType) ast.Expression {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return pattern
+	}
+
+	for {
+		p.nextToken()
+		if p.curTokenIs(token.TOKEN_ELLIPSIS) {
+			pattern.Rest = p.parseRestPattern()
+			if pattern.Rest == nil {
+				return nil
+			}
+			break
+		}
+
+		elem := p.parsePattern()
+		if elem == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, elem)
+
+		if !p.peekTokenIs(token.TOKEN_COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+	return pattern
+}
+
+//{name, age: years, "home": {city}, ...others}
+func (p *Parser) parseHashPattern() ast.Expression {
+	pattern := &ast.HashPattern{Token: p.curToken}
+	if p.peekTokenIs(token.TOKEN_RBRACE) {
+		p.nextToken()
+		return pattern
+	}
+
+	for {
+		p.nextToken()
+		if p.curTokenIs(token.TOKEN_ELLIPSIS) {
+			pattern.Rest = p.parseRestPattern()
+			if pattern.Rest == nil {
+				return nil
+			}
+			break
+		}
+
+		var key, value ast.Expression
+		if p.curTokenIs(token.TOKEN_IDENTIFIER) {
+			key = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		} else {
+			key = p.parseExpression(LOWEST)
+		}
+
+		if p.peekTokenIs(token.TOKEN_COLON) { //key: pattern
+			p.nextToken()
+			p.nextToken()
+			value = p.parsePattern()
+		} else if _, ok := key.(*ast.Identifier); ok { //shorthand, e.g. {name}
+			value = p.parsePattern()
+		} else {
+			p.peekError(token.TOKEN_COLON)
+		}
+		if value == nil {
+			return nil
+		}
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Values = append(pattern.Values, value)
+
+		if !p.peekTokenIs(token.TOKEN_COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.TOKEN_RBRACE) {
+		return nil
+	}
+	return pattern
+}
+
+//'...rest' of a pattern, the current token is '...'
+func (p *Parser) parseRestPattern() ast.Expression {
+	p.nextToken()
+	if !p.curTokenIs(token.TOKEN_IDENTIFIER) && p.curToken.Literal != "_" {
+		msg := fmt.Sprintf("Syntax Error:%v- expected token to be identifier|underscore after '...', got %s instead.", p.curToken.Pos, p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+		return nil
+	}
+	return p.parsePattern()
+}
+
+//assignPattern converts the array or tuple literal on the left hand side of an assignment
+//to a pattern, e.g. [a, b] = [b, a], [first, ...rest] = arr. Other expressions are
+//returned unchanged.
+func (p *Parser) assignPattern(expr ast.Expression) ast.Expression {
+	var tok token.Token
+	var members []ast.Expression
+	switch lit := expr.(type) {
+	case *ast.ArrayLiteral:
+		tok, members = lit.Token, lit.Members
+	case *ast.TupleLiteral:
+		tok, members = lit.Token, lit.Members
+	default:
+		return expr
+	}
+
+	pattern := &ast.ArrayPattern{Token: tok}
+	for i, m := range members {
+		if spread, ok := m.(*ast.SpreadExpression); ok {
+			if i != len(members)-1 {
+				msg := fmt.Sprintf("Syntax Error:%v- '...' must be the last element of the left hand side of an assignment", spread.Pos())
+				p.errors = append(p.errors, msg)
+				p.errorLines = append(p.errorLines, spread.Pos().Sline())
+				return expr
+			}
+			pattern.Rest = spread.Value
+			break
+		}
+		pattern.Elements = append(pattern.Elements, p.assignPattern(m))
+	}
+	return pattern
+}
+
+//isHashPatternAssign reports whether the '{' at the start of a statement begins a hash
+//pattern assignment(e.g. {name, age: years} = h) rather than a block. It scans to the
+//matching '}' with a copy of the lexer, so no tokens are consumed.
+func (p *Parser) isHashPatternAssign() bool {
+	l := *p.l
+	tok := p.peekToken
+	for depth := 1; ; tok = l.NextToken() {
+		switch tok.Type {
+		case token.TOKEN_LBRACE:
+			depth++
+		case token.TOKEN_RBRACE:
+			depth--
+			if depth == 0 {
+				return l.NextToken().Type == token.TOKEN_ASSIGN
+			}
+		case token.TOKEN_EOF, token.TOKEN_ILLEGAL:
+			return false
+		}
+	}
+}
+
+//{name, age: years} = h
+func (p *Parser) parseHashPatternAssignStatement() *ast.ExpressionStatement {
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	pattern := p.parseHashPattern()
+	if pattern == nil || !p.expectPeek(token.TOKEN_ASSIGN) {
+		return nil
+	}
+
+	a := &ast.AssignExpression{Token: p.curToken, Name: pattern}
+	p.nextToken()
+	a.Value = p.parseExpression(LOWEST)
+	stmt.Expression = a
+
+	if p.peekTokenIs(token.TOKEN_SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
 func (p *Parser) parseMultiAssignStatement(expr ast.Expression) *ast.MultiAssignStatement {
 	tok := token.Token{Pos: p.curToken.Pos, Type: token.TOKEN_ASSIGN, Literal: "="}
 	stmt := &ast.MultiAssignStatement{Token: tok}
@@ -441,7 +703,7 @@ func (p *Parser) parseMultiAssignStatement(expr ast.Expression) *ast.MultiAssign
 	//names
 	for {
 		n := p.parseExpression(ASSIGN)
-		stmt.Names = append(stmt.Names, n)
+		stmt.Names = append(stmt.Names, p.assignPattern(n))
 		if p.peekTokenIs(token.TOKEN_ASSIGN) {
 			p.nextToken()
 			p.nextToken()
@@ -516,6 +778,26 @@ func (p *Parser) parseTailCallStatement() *ast.TailCallStatement {
 	return stmt
 }
 
+func (p *Parser) parseDeferStatement() *ast.DeferStatement {
+	stmt := &ast.DeferStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Call = p.parseExpressionStatement().Expression
+	switch call := stmt.Call.(type) {
+	case *ast.CallExpression:
+		return stmt
+	case *ast.MethodCallExpression:
+		if _, ok := call.Call.(*ast.CallExpression); ok && !call.Optional {
+			return stmt
+		}
+	}
+
+	msg := fmt.Sprintf("Syntax Error:%v- 'defer' must be followed by a function call", stmt.Token.Pos)
+	p.errors = append(p.errors, msg)
+	p.errorLines = append(p.errorLines, stmt.Token.Pos.Sline())
+	return nil
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	blockStmt := &ast.BlockStatement{Token: p.curToken}
 	blockStmt.Statements = []ast.Statement{}
@@ -575,6 +857,9 @@ func (p *Parser) parseAssignExpression(name ast.Expression) ast.Expression {
 		return nil
 	}
 	a := &ast.AssignExpression{Token: p.curToken, Name: name}
+	if p.curTokenIs(token.TOKEN_ASSIGN) {
+		a.Name = p.assignPattern(name)
+	}
 
 	p.nextToken()
 	a.Value = p.parseExpression(LOWEST)
@@ -608,7 +893,24 @@ func (p *Parser) parseFatArrow(left ast.Expression) ast.Expression {
 		for _, v := range exprType.Members {
 			switch param := v.(type) {
 			case *ast.Identifier:
+				if fn.Values != nil {
+					p.nonDefaultParamError(param)
+					return nil
+				}
 				fn.Parameters = append(fn.Parameters, param)
+			case *ast.AssignExpression: //(x, y = 2) => x + y
+				name, ok := param.Name.(*ast.Identifier)
+				if !ok || param.Token.Type != token.TOKEN_ASSIGN {
+					msg := fmt.Sprintf("Syntax Error:%v- Arrow function expects a list of identifiers as arguments", param.Pos())
+					p.errors = append(p.errors, msg)
+					p.errorLines = append(p.errorLines, param.Pos().Sline())
+					return nil
+				}
+				if fn.Values == nil {
+					fn.Values = make(map[string]ast.Expression)
+				}
+				fn.Parameters = append(fn.Parameters, name)
+				fn.Values[name.Value] = param.Value
 			default:
 				msg := fmt.Sprintf("Syntax Error:%v- Arrow function expects a list of identifiers as arguments", param.Pos())
 				p.errors = append(p.errors, msg)
@@ -623,6 +925,10 @@ func (p *Parser) parseFatArrow(left ast.Expression) ast.Expression {
 		return nil
 	}
 
+	savedAllArgs := p.allArgs
+	p.allArgs = false
+	defer func() { p.allArgs = savedAllArgs }()
+
 	p.nextToken()
 	if p.curTokenIs(token.TOKEN_LBRACE) { //if it's block, we use parseBlockStatement
 		fn.Body = p.parseBlockStatement()
@@ -637,6 +943,7 @@ func (p *Parser) parseFatArrow(left ast.Expression) ast.Expression {
 			},
 		}
 	}
+	fn.AllArgs = p.allArgs
 	return fn
 }
 
@@ -658,7 +965,8 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 
 	// if the token is '**', we process it specially. e.g. 3 ** 2 ** 3 = 3 ** (2 ** 3)
 	// i.e. Exponent operator '**'' has right-to-left associativity
-	if p.curTokenIs(token.TOKEN_POWER) {
+	// '??' is also right associative: a ?? b ?? c = a ?? (b ?? c)
+	if p.curTokenIs(token.TOKEN_POWER) || p.curTokenIs(token.TOKEN_NULLISH) {
 		precedence--
 	}
 
@@ -684,6 +992,22 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+//cond ? a : b
+//The ternary operator is right associative: a ? b : c ? d : e = a ? b : (c ? d : e)
+func (p *Parser) parseTernaryExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.TernaryExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	expression.IfTrue = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.TOKEN_COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.IfFalse = p.parseExpression(TERNARY - 1)
+	return expression
+}
+
 func (p *Parser) isCompareOperator() bool {
 	return p.peekTokenIs(token.TOKEN_LT) || p.peekTokenIs(token.TOKEN_LE) ||
 		p.peekTokenIs(token.TOKEN_GT) || p.peekTokenIs(token.TOKEN_GE) ||
@@ -709,13 +1033,17 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 		return &ast.TupleLiteral{Token: savedToken, Members: []ast.Expression{}}
 	}
 
-	exp := p.parseExpression(LOWEST)
+	exp := p.parseElement()
 
 	if p.peekTokenIs(token.TOKEN_COMMA) {
 		p.nextToken()
 		ret := p.parseTupleExpression(savedToken, exp)
 		return ret
 	}
+	if _, ok := exp.(*ast.SpreadExpression); ok && p.peekTokenIs(token.TOKEN_RPAREN) { //e.g. (...arr)
+		p.nextToken()
+		return &ast.TupleLiteral{Token: savedToken, Members: []ast.Expression{exp}}
+	}
 
 	if !p.expectPeek(token.TOKEN_RPAREN) {
 		return nil
@@ -739,10 +1067,16 @@ func (p *Parser) parseInfixIllegalExpression() ast.Expression {
 }
 
 func (p *Parser) parseNumber() ast.Expression {
+	literal := p.curToken.Literal
+	if hasRadixPrefix(literal) || !strings.ContainsAny(literal, ".eE") {
+		return p.parseInteger()
+	}
+
 	lit := &ast.NumberLiteral{Token: p.curToken}
 
-	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
-	if err != nil {
+	str, ok := removeUnderscores(literal)
+	value, err := strconv.ParseFloat(str, 64)
+	if !ok || err != nil {
 		msg := fmt.Sprintf("Syntax Error:%v - could not parse %q as float", p.curToken.Pos, p.curToken.Literal)
 		p.errors = append(p.errors, msg)
 		p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
@@ -752,7 +1086,138 @@ func (p *Parser) parseNumber() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseInteger() ast.Expression {
+	lit := &ast.IntegerLiteral{Token: p.curToken}
+
+	value, err := parseIntLiteral(p.curToken.Literal)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			//too large for int64, automatically becomes a bigint
+			return p.parseBigInt()
+		}
+		msg := fmt.Sprintf("Syntax Error:%v - could not parse %q as integer", p.curToken.Pos, p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseBigInt() ast.Expression {
+	lit := &ast.BigIntLiteral{Token: p.curToken}
+
+	value, ok := parseBigIntLiteral(strings.TrimSuffix(p.curToken.Literal, "n"))
+	if !ok {
+		msg := fmt.Sprintf("Syntax Error:%v - could not parse %q as bigint", p.curToken.Pos, p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseDecimal() ast.Expression {
+	lit := &ast.DecimalLiteral{Token: p.curToken}
+
+	str, ok := removeUnderscores(strings.TrimSuffix(p.curToken.Literal, "d"))
+
+	//exponent, e.g. 1.5e3d
+	var exp int64
+	if idx := strings.IndexAny(str, "eE"); idx != -1 && ok {
+		var err error
+		exp, err = strconv.ParseInt(str[idx+1:], 10, 32)
+		ok = err == nil
+		str = str[:idx]
+	}
+
+	var scale int64
+	if idx := strings.Index(str, "."); idx != -1 {
+		scale = int64(len(str) - idx - 1)
+		str = str[:idx] + str[idx+1:]
+	}
+
+	var value *big.Int
+	if ok {
+		value, ok = new(big.Int).SetString(str, 10)
+	}
+	if !ok {
+		msg := fmt.Sprintf("Syntax Error:%v - could not parse %q as decimal", p.curToken.Pos, p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+		return nil
+	}
+
+	scale -= exp
+	if scale < 0 { //e.g. 1.5e3d => 1500
+		value.Mul(value, new(big.Int).Exp(big.NewInt(10), big.NewInt(-scale), nil))
+		scale = 0
+	}
+	lit.Unscaled = value
+	lit.Scale = int32(scale)
+	return lit
+}
+
+//0x, 0o or 0b
+func hasRadixPrefix(literal string) bool {
+	if len(literal) < 2 || literal[0] != '0' {
+		return false
+	}
+	return strings.ContainsRune("xXoObB", rune(literal[1]))
+}
+
+//removeUnderscores removes the digit separators, e.g. 1_000_000 => 1000000.
+//The returned bool is false if a '_' is not between two digits.
+func removeUnderscores(literal string) (string, bool) {
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '_' {
+			continue
+		}
+		if i == 0 || i == len(literal)-1 || !isDigit(literal[i-1]) || !isDigit(literal[i+1]) {
+			return "", false
+		}
+	}
+	return strings.Replace(literal, "_", "", -1), true
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func isLetter(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+}
+
+//parseIntLiteral parses integer literals like 123, 1_000, 0xFF, 0o755 and 0b1010
+func parseIntLiteral(literal string) (int64, error) {
+	if hasRadixPrefix(literal) {
+		return strconv.ParseInt(literal, 0, 64) //base 0 also checks the '_'s
+	}
+
+	str, ok := removeUnderscores(literal)
+	if !ok {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseInt(str, 10, 64)
+}
+
+func parseBigIntLiteral(literal string) (*big.Int, bool) {
+	if hasRadixPrefix(literal) {
+		return new(big.Int).SetString(literal, 0)
+	}
+
+	str, ok := removeUnderscores(literal)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Int).SetString(str, 10)
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
+	if p.curToken.Literal == "$_" {
+		p.allArgs = true
+	}
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
@@ -761,27 +1226,205 @@ func (p *Parser) parseBooleanLiteral() ast.Expression {
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
-	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+	lit := &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal, Raw: p.curTokenIs(token.TOKEN_RAW_STRING)}
+	if !lit.Raw {
+		lit.Value, lit.Parts = p.parseInterpolation(lit.Value)
+	}
+	return lit
+}
+
+//format spec of an interpolated expression, e.g. "${price:.2f}", "${name:-10s}"
+var formatSpecRe = regexp.MustCompile(`^[-+#0]*[0-9]*(\.[0-9]+)?[bcdeEfFgGoqsStTvxX]$`)
+
+//parseInterpolation splits a string literal into text and expressions:
+//    "Hello $name, you owe ${price * 2:.2f}" => ["Hello ", name, ", you owe ", price * 2 (.2f)]
+//'\$' is a literal '$'(see lexer.EscapeMark). If the string has no interpolation, the returned parts is nil,
+//and the returned string is the unescaped text.
+func (p *Parser) parseInterpolation(str string) (string, []*ast.StringPart) {
+	var parts []*ast.StringPart
+	var text strings.Builder
+
+	for i := 0; i < len(str); i++ {
+		ch := str[i]
+		if ch == lexer.EscapeMark && i+1 < len(str) { //marked by the lexer, e.g. \$var => $var
+			text.WriteByte(str[i+1])
+			i++
+			continue
+		}
+		if ch != '$' || i+1 >= len(str) {
+			text.WriteByte(ch)
+			continue
+		}
+
+		var exprStr, spec string
+		var end int //index of the last character of the interpolation
+		if str[i+1] == '{' {
+			end = matchingBrace(str, i+1)
+			if end == -1 { //e.g. "my ${var", treated as normal text
+				text.WriteByte(ch)
+				continue
+			}
+			exprStr, spec = splitFormatSpec(str[i+2 : end])
+		} else if str[i+1] == '_' || isLetter(str[i+1]) {
+			end = i + 1
+			for end+1 < len(str) && (str[end+1] == '_' || isLetter(str[end+1]) || isDigit(str[end+1])) {
+				end++
+			}
+			exprStr = str[i+1 : end+1]
+			tok := token.LookupIdent(exprStr)
+			if tok != token.TOKEN_IDENTIFIER && tok != token.TOKEN_TRUE && tok != token.TOKEN_FALSE && tok != token.TOKEN_NIL {
+				text.WriteString(str[i : end+1]) //a keyword, e.g. "$if", treated as normal text
+				i = end
+				continue
+			}
+		} else { //e.g. "$5", "a$"
+			text.WriteByte(ch)
+			continue
+		}
+
+		expr := p.parseInterpolatedExpression(exprStr)
+		if expr == nil {
+			return str, nil
+		}
+		if text.Len() > 0 {
+			parts = append(parts, &ast.StringPart{Text: text.String()})
+			text.Reset()
+		}
+		parts = append(parts, &ast.StringPart{Expr: expr, Format: spec})
+		i = end
+	}
+
+	if parts == nil {
+		return text.String(), nil
+	}
+	if text.Len() > 0 {
+		parts = append(parts, &ast.StringPart{Text: text.String()})
+	}
+	return str, parts
+}
+
+//parseInterpolatedExpression parses the expression of "${expr}" with a new parser.
+func (p *Parser) parseInterpolatedExpression(exprStr string) ast.Expression {
+	ps := NewParser(lexer.NewExprLexer(exprStr, p.curToken.Pos))
+	program := ps.ParseProgram()
+	if len(ps.errors) == 0 && len(program.Statements) == 1 {
+		if stmt, ok := program.Statements[0].(*ast.ExpressionStatement); ok && stmt.Expression != nil {
+			return stmt.Expression
+		}
+	}
+
+	msg := fmt.Sprintf("Syntax Error:%v- invalid expression '%s' in string interpolation", p.curToken.Pos, exprStr)
+	p.errors = append(p.errors, msg)
+	p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+	return nil
+}
+
+//matchingBrace returns the index of the '}' which closes the '{' at 'start',
+//skipping braces inside quoted strings. It returns -1 if not found.
+func matchingBrace(str string, start int) int {
+	depth := 0
+	for i := start; i < len(str); i++ {
+		switch str[i] {
+		case '"':
+			for i++; i < len(str) && str[i] != '"'; i++ {
+				if str[i] == '\\' {
+					i++
+				}
+			}
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+//splitFormatSpec splits "expr:spec" into expression and format spec.
+//The spec must be after the last top-level ':' which is not part of a
+//ternary, and must look like a printf verb, so "${a ? b : c}" and
+//"${arr[1:2]}" are not split.
+func splitFormatSpec(str string) (string, string) {
+	depth := 0
+	colon := -1
+	ternary := 0 //number of '?' waiting for a ':'
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '"':
+			for i++; i < len(str) && str[i] != '"'; i++ {
+				if str[i] == '\\' {
+					i++
+				}
+			}
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '?':
+			if i+1 < len(str) && str[i+1] == '?' { // '??'
+				i++
+			} else if i+1 < len(str) && (str[i+1] == '.' || str[i+1] == '[') {
+				//optional chaining: '?.', '?['
+			} else if depth == 0 {
+				ternary++
+			}
+		case ':':
+			if depth == 0 {
+				if ternary > 0 {
+					ternary--
+				} else {
+					colon = i
+				}
+			}
+		}
+	}
+
+	if colon != -1 && formatSpecRe.MatchString(str[colon+1:]) {
+		return str[:colon], str[colon+1:]
+	}
+	return str, ""
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
-	array.Members, _ = p.parseExpressionList(token.TOKEN_RBRACKET)
+	if p.peekTokenIs(token.TOKEN_RBRACKET) {
+		p.nextToken()
+		array.Members = []ast.Expression{}
+		return array
+	}
+
+	p.nextToken()
+	first := p.parseElement()
+	if p.peekTokenIs(token.TOKEN_FOR) { //e.g. [x * 2 for x in xs if x > 0]
+		if spread, ok := first.(*ast.SpreadExpression); ok {
+			msg := fmt.Sprintf("Syntax Error:%v- '...' can not be used in the element of a comprehension", spread.Pos())
+			p.errors = append(p.errors, msg)
+			p.errorLines = append(p.errorLines, spread.Pos().Sline())
+			return nil
+		}
+		ac := &ast.ArrayComprehension{Token: array.Token, Element: first}
+		if ac.Fors = p.parseComprehensionFors(); ac.Fors == nil {
+			return nil
+		}
+		if !p.expectPeek(token.TOKEN_RBRACKET) {
+			return nil
+		}
+		return ac
+	}
+
+	array.Members, _ = p.parseExpressionList(first, token.TOKEN_RBRACKET)
 	return array
 }
 
-func (p *Parser) parseExpressionList(end token.TokenType) ([]ast.Expression, bool) {
+//parseExpressionList parses the rest of a list, whose first element 'first' is already parsed.
+func (p *Parser) parseExpressionList(first ast.Expression, end token.TokenType) ([]ast.Expression, bool) {
 	gotEllipsis := false
 	success := false
 
-	list := []ast.Expression{}
-	if p.peekTokenIs(end) {
-		p.nextToken()
-		return list, false
-	}
-
-	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list := []ast.Expression{first}
 	gotEllipsis, success = p.checkEllipsis() //e.g. call(args...)
 	if !success {
 		return nil, false
@@ -790,7 +1433,7 @@ func (p *Parser) parseExpressionList(end token.TokenType) ([]ast.Expression, boo
 	for p.peekTokenIs(token.TOKEN_COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseElement())
 
 		gotEllipsis, success = p.checkEllipsis()
 		if !success {
@@ -823,11 +1466,37 @@ func (p *Parser) checkEllipsis() (bool, bool) {
 	return gotEllipsis, true
 }
 
+//parseElement parses an element of array and tuple literals, or a positional argument
+//of calls, which could be a spread, e.g. [...a, 4]
+func (p *Parser) parseElement() ast.Expression {
+	if p.curTokenIs(token.TOKEN_ELLIPSIS) {
+		return p.parseSpreadExpression()
+	}
+	return p.parseExpression(LOWEST)
+}
+
+func (p *Parser) parseSpreadExpression() ast.Expression {
+	spread := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+	return spread
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken, Order: []ast.Expression{}}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
 	for !p.peekTokenIs(token.TOKEN_RBRACE) {
 		p.nextToken()
+		if p.curTokenIs(token.TOKEN_ELLIPSIS) { //e.g. {...defaults, "port": 80}
+			spread := p.parseSpreadExpression()
+			hash.Pairs[spread] = spread.(*ast.SpreadExpression).Value
+			hash.Order = append(hash.Order, spread)
+			if !p.peekTokenIs(token.TOKEN_RBRACE) && !p.expectPeek(token.TOKEN_COMMA) {
+				return nil
+			}
+			continue
+		}
+
 		key := p.parseExpression(LOWEST)
 		if !p.expectPeek(token.TOKEN_COLON) {
 			return nil
@@ -835,6 +1504,16 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
+		if len(hash.Order) == 0 && p.peekTokenIs(token.TOKEN_FOR) { //e.g. {k: v * 2 for k, v in h}
+			hc := &ast.HashComprehension{Token: hash.Token, Key: key, Value: value}
+			if hc.Fors = p.parseComprehensionFors(); hc.Fors == nil {
+				return nil
+			}
+			if !p.expectPeek(token.TOKEN_RBRACE) {
+				return nil
+			}
+			return hc
+		}
 		hash.Pairs[key] = value
 		hash.Order = append(hash.Order, key)
 		if !p.peekTokenIs(token.TOKEN_RBRACE) && !p.expectPeek(token.TOKEN_COMMA) {
@@ -849,6 +1528,42 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	return hash
 }
 
+//parseComprehensionFors parses the 'for' clauses of a comprehension, each of them
+//could be followed by 'if' conditions:
+//    for x in xs if x > 0 for y in ys if y != x
+func (p *Parser) parseComprehensionFors() []*ast.ComprehensionFor {
+	var fors []*ast.ComprehensionFor
+	for p.peekTokenIs(token.TOKEN_FOR) {
+		p.nextToken()
+		f := &ast.ComprehensionFor{Token: p.curToken}
+
+		p.nextToken()
+		if f.Key = p.parsePattern(); f.Key == nil {
+			return nil
+		}
+		if p.peekTokenIs(token.TOKEN_COMMA) { //for k, v in h
+			p.nextToken()
+			p.nextToken()
+			if f.Value = p.parsePattern(); f.Value == nil {
+				return nil
+			}
+		}
+		if !p.expectPeek(token.TOKEN_IN) {
+			return nil
+		}
+
+		p.nextToken()
+		f.X = p.parseExpression(LOWEST)
+		for p.peekTokenIs(token.TOKEN_IF) {
+			p.nextToken()
+			p.nextToken()
+			f.Conds = append(f.Conds, p.parseExpression(LOWEST))
+		}
+		fors = append(fors, f)
+	}
+	return fors
+}
+
 // parses a regular-expression
 func (p *Parser) parseRegexpLiteral() ast.Expression {
 	return &ast.RegExLiteral{Token: p.curToken, Value: p.curToken.Literal}
@@ -871,7 +1586,7 @@ func (p *Parser) parseTupleExpression(tok token.Token, expr ast.Expression) ast.
 				ret := &ast.TupleLiteral{Token: tok, Members: members}
 				return ret
 			}
-			members = append(members, p.parseExpression(LOWEST))
+			members = append(members, p.parseElement())
 			oldToken = p.curToken
 			p.nextToken()
 		default:
@@ -895,86 +1610,188 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	if !p.expectPeek(token.TOKEN_LPAREN) {
 		return nil
 	}
-	lit.Parameters, lit.Variadic = p.parseFunctionParameters()
+	lit.Parameters, lit.Values, lit.Patterns, lit.Variadic = p.parseFunctionParameters()
 	if !p.expectPeek(token.TOKEN_LBRACE) {
 		return nil
 	}
+
+	savedAllArgs := p.allArgs
+	p.allArgs = false
 	lit.Body = p.parseBlockStatement()
+	lit.AllArgs = p.allArgs
+	p.allArgs = savedAllArgs
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, bool) {
+//fn xxx(a, b = 10, args...)
+//A parameter with a default value can not be followed by a parameter without one(except the variadic one).
+//parseFunctionParameters returns the parameters, their default values, the destructured
+//parameters and whether the last parameter is variadic. A destructured parameter, e.g.
+//fn f((x, y)), is named by its pattern, so it never clashes with other names.
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, map[string]ast.Expression, map[string]ast.Expression, bool) {
 	gotEllipsis := false
 	success := false
 
 	identifiers := []*ast.Identifier{}
+	var values map[string]ast.Expression
+	var patterns map[string]ast.Expression
 	if p.peekTokenIs(token.TOKEN_RPAREN) {
 		p.nextToken()
-		return identifiers, false
-	}
-	p.nextToken()
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
-	gotEllipsis, success = p.checkEllipsis() //e.g. fn xxx(args...)
-	if !success {
-		return nil, false
+		return identifiers, nil, nil, false
 	}
 
-	for p.peekTokenIs(token.TOKEN_COMMA) {
-		p.nextToken()
+	for {
 		p.nextToken()
 		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if p.curTokenIs(token.TOKEN_LPAREN) || p.curTokenIs(token.TOKEN_LBRACKET) || p.curTokenIs(token.TOKEN_LBRACE) {
+			pattern := p.parsePattern()
+			if pattern == nil {
+				return nil, nil, nil, false
+			}
+			ident.Value = pattern.String()
+			if patterns == nil {
+				patterns = make(map[string]ast.Expression)
+			}
+			patterns[ident.Value] = pattern
+		}
 		identifiers = append(identifiers, ident)
-		gotEllipsis, success = p.checkEllipsis()
+		gotEllipsis, success = p.checkEllipsis() //e.g. fn xxx(args...)
 		if !success {
-			return nil, false
+			return nil, nil, nil, false
+		}
+
+		if p.peekTokenIs(token.TOKEN_ASSIGN) { //e.g. fn xxx(port = 8080)
+			p.nextToken()
+			p.nextToken()
+			if values == nil {
+				values = make(map[string]ast.Expression)
+			}
+			values[ident.Value] = p.parseExpression(LOWEST)
+		} else if values != nil && !gotEllipsis {
+			p.nonDefaultParamError(ident)
+			return nil, nil, nil, false
+		}
+
+		if !p.peekTokenIs(token.TOKEN_COMMA) {
+			break
 		}
+		p.nextToken()
 	}
 
 	if !p.expectPeek(token.TOKEN_RPAREN) {
-		return nil, false
+		return nil, nil, nil, false
 	}
-	return identifiers, gotEllipsis
+	return identifiers, values, patterns, gotEllipsis
+}
+
+func (p *Parser) nonDefaultParamError(param *ast.Identifier) {
+	msg := fmt.Sprintf("Syntax Error:%v- parameter '%s' without a default value follows a parameter with a default value", param.Pos(), param.Value)
+	p.errors = append(p.errors, msg)
+	p.errorLines = append(p.errorLines, param.Pos().Sline())
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments, exp.Variadic = p.parseExpressionList(token.TOKEN_RPAREN)
+	exp.Arguments, exp.NamedArgs, exp.Variadic = p.parseCallArguments()
 	return exp
 }
 
-/*
-func (p *Parser) parseCallArguments() []ast.Expression {
+//parseCallArguments parses the arguments of a call, keyword arguments must follow the positional ones:
+//    connect("db", 5432, timeout: 5, retry: true)
+func (p *Parser) parseCallArguments() ([]ast.Expression, []*ast.NamedArgument, bool) {
+	gotEllipsis := false
+	success := false
+
 	args := []ast.Expression{}
+	var namedArgs []*ast.NamedArgument
 	if p.peekTokenIs(token.TOKEN_RPAREN) {
 		p.nextToken()
-		return args
+		return args, nil, false
 	}
-	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
-	for p.peekTokenIs(token.TOKEN_COMMA) {
+
+	for {
 		p.nextToken()
+		if p.curTokenIs(token.TOKEN_IDENTIFIER) && p.peekTokenIs(token.TOKEN_COLON) { //keyword argument
+			name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			for _, na := range namedArgs {
+				if na.Name.Value == name.Value {
+					msg := fmt.Sprintf("Syntax Error:%v- duplicate keyword argument '%s'", p.curToken.Pos, name.Value)
+					p.errors = append(p.errors, msg)
+					p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+					return nil, nil, false
+				}
+			}
+			p.nextToken()
+			p.nextToken()
+			namedArgs = append(namedArgs, &ast.NamedArgument{Name: name, Value: p.parseExpression(LOWEST)})
+		} else {
+			if namedArgs != nil {
+				msg := fmt.Sprintf("Syntax Error:%v- positional argument follows keyword argument", p.curToken.Pos)
+				p.errors = append(p.errors, msg)
+				p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+				return nil, nil, false
+			}
+			args = append(args, p.parseElement())
+			gotEllipsis, success = p.checkEllipsis() //e.g. call(args...)
+			if !success {
+				return nil, nil, false
+			}
+		}
+
+		if !p.peekTokenIs(token.TOKEN_COMMA) {
+			break
+		}
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
 	}
+
 	if !p.expectPeek(token.TOKEN_RPAREN) {
-		return nil
+		return nil, nil, false
 	}
-	return args
+	return args, namedArgs, gotEllipsis
 }
-*/
 
+//left[index]
+//left[start:stop:step], each part of the slice could be omitted, e.g. arr[1:], str[::-1]
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left, Optional: p.curTokenIs(token.TOKEN_OPTINDEX)}
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+
+	var start ast.Expression
+	if !p.curTokenIs(token.TOKEN_COLON) {
+		start = p.parseExpression(LOWEST)
+		if !p.peekTokenIs(token.TOKEN_COLON) { //not a slice
+			exp.Index = start
+			if !p.expectPeek(token.TOKEN_RBRACKET) {
+				return nil
+			}
+			return exp
+		}
+		p.nextToken()
+	}
+
+	slice := &ast.SliceExpression{Token: p.curToken, Start: start}
+	slice.Stop = p.parseSlicePart()
+	if p.peekTokenIs(token.TOKEN_COLON) {
+		p.nextToken()
+		slice.Step = p.parseSlicePart()
+	}
+	exp.Index = slice
+
 	if !p.expectPeek(token.TOKEN_RBRACKET) {
 		return nil
 	}
-
 	return exp
 }
 
+//parseSlicePart parses the part after a ':' of a slice, returns nil if it's omitted.
+func (p *Parser) parseSlicePart() ast.Expression {
+	if p.peekTokenIs(token.TOKEN_COLON) || p.peekTokenIs(token.TOKEN_RBRACKET) {
+		return nil
+	}
+	p.nextToken()
+	return p.parseExpression(LOWEST)
+}
+
 func (p *Parser) parseNilExpression() ast.Expression {
 	return &ast.NilLiteral{Token: p.curToken}
 }
@@ -1034,7 +1851,7 @@ func (p *Parser) parseConditionalExpression() *ast.IfConditionExpr {
 }
 
 func (p *Parser) parseMethodCallExpression(obj ast.Expression) ast.Expression {
-	methodCall := &ast.MethodCallExpression{Token: p.curToken, Object: obj}
+	methodCall := &ast.MethodCallExpression{Token: p.curToken, Object: obj, Optional: p.curTokenIs(token.TOKEN_OPTDOT)}
 	p.nextToken()
 
 	name := p.parseIdentifier()
@@ -1102,8 +1919,12 @@ func (p *Parser) parseForLoopExpression() ast.Expression {
 		return r
 	}
 
-	if p.peekTokenIs(token.TOKEN_LPAREN) { //for (init; cond; updater) { block }
-		r = p.parseCForLoopExpression(curToken)
+	if p.peekTokenIs(token.TOKEN_LPAREN) {
+		if p.isForEachInParens() { //for (k, [a, b]) in xxx { block }
+			r = p.parseForEachInParens(curToken)
+		} else { //for (init; cond; updater) { block }
+			r = p.parseCForLoopExpression(curToken)
+		}
 		p.loopDepth--
 		return r
 	}
@@ -1117,6 +1938,8 @@ func (p *Parser) parseForLoopExpression() ast.Expression {
 		} else {
 			r = p.parseForEachArrayExpression(curToken, p.curToken.Literal)
 		}
+	} else if p.curTokenIs(token.TOKEN_LBRACKET) { //for [a, b] in xxx { block }, use 'for ({a, b}) in xxx' for hashes
+		r = p.parseForEachPatternExpression(curToken)
 	} else {
 		msg := fmt.Sprintf("Syntax Error:%v- for loop must be followed by an underscore or identifier. got %s", p.curToken.Pos, p.curToken.Literal)
 		p.errors = append(p.errors, msg)
@@ -1213,25 +2036,112 @@ func (p *Parser) parseForEachArrayExpression(curToken token.Token, variable stri
 //for key, value in hash {}
 //key & value could be '_' but not both
 func (p *Parser) parseForEachMapExpression(curToken token.Token, key string) ast.Expression {
-	loop := &ast.ForEachMapLoop{Token: curToken}
-	loop.Key = key
-
+	keyIdent := &ast.Identifier{Token: p.curToken, Value: key}
 	if !p.expectPeek(token.TOKEN_COMMA) {
 		return nil
 	}
 
-	p.nextToken() //skip ','
-	if p.curToken.Literal == "_" {
-		//do nothing
-	} else if !p.curTokenIs(token.TOKEN_IDENTIFIER) {
-		msg := fmt.Sprintf("Syntax Error:%v- for loop must be followed by an identifier. got %s", p.curToken.Pos, p.curToken.Literal)
-		p.errors = append(p.errors, msg)
-		p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+	p.nextToken()             //skip ','
+	value := p.parsePattern() //e.g. for k, [a, b] in hash {}
+	if value == nil {
+		return nil
+	}
+	return p.parseForEachMapPattern(curToken, keyIdent, value)
+}
+
+//isForEachInParens reports whether the '(' after 'for' is followed by a
+//foreach loop's variables, i.e. the matching ')' is followed by 'in'.
+func (p *Parser) isForEachInParens() bool {
+	l := *p.l //look ahead with a copy of the lexer
+	depth := 1
+	for {
+		tok := l.NextToken()
+		switch tok.Type {
+		case token.TOKEN_EOF:
+			return false
+		case token.TOKEN_LPAREN:
+			depth++
+		case token.TOKEN_RPAREN:
+			depth--
+			if depth == 0 {
+				return l.NextToken().Type == token.TOKEN_IN
+			}
+		}
+	}
+}
+
+//for (item) in array {}
+//for (key, value) in hash {}
+//'item', 'key' and 'value' could be patterns.
+func (p *Parser) parseForEachInParens(curToken token.Token) ast.Expression {
+	p.nextToken() //skip 'for'
+	p.nextToken() //skip '('
+
+	key := p.parsePattern()
+	if key == nil {
+		return nil
+	}
+
+	var value ast.Expression
+	if p.peekTokenIs(token.TOKEN_COMMA) {
+		p.nextToken()
+		p.nextToken()
+		value = p.parsePattern()
+		if value == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.TOKEN_RPAREN) {
+		return nil
+	}
+
+	if value == nil {
+		return p.parseForEachArrayPattern(curToken, key)
+	}
+	return p.parseForEachMapPattern(curToken, key, value)
+}
+
+//for [a, b] in array {}
+//for [a, b], value in hash {}
+func (p *Parser) parseForEachPatternExpression(curToken token.Token) ast.Expression {
+	key := p.parsePattern()
+	if key == nil {
 		return nil
 	}
-	loop.Value = p.curToken.Literal
+	if !p.peekTokenIs(token.TOKEN_COMMA) {
+		return p.parseForEachArrayPattern(curToken, key)
+	}
+
+	p.nextToken()
+	p.nextToken()
+	value := p.parsePattern()
+	if value == nil {
+		return nil
+	}
+	return p.parseForEachMapPattern(curToken, key, value)
+}
+
+func (p *Parser) parseForEachArrayPattern(curToken token.Token, pattern ast.Expression) ast.Expression {
+	if ident, ok := pattern.(*ast.Identifier); ok {
+		return p.parseForEachArrayExpression(curToken, ident.Value)
+	}
+
+	r := p.parseForEachArrayExpression(curToken, pattern.String())
+	if loop, ok := r.(*ast.ForEachArrayLoop); ok {
+		loop.Pattern = pattern
+	}
+	return r
+}
 
-	if loop.Key == "_" && loop.Value == "_" { //for _, _ in xxx { block }
+func (p *Parser) parseForEachMapPattern(curToken token.Token, key, value ast.Expression) ast.Expression {
+	loop := &ast.ForEachMapLoop{Token: curToken, Key: key.String(), Value: value.String()}
+	if _, ok := key.(*ast.Identifier); !ok {
+		loop.KeyPattern = key
+	}
+	if _, ok := value.(*ast.Identifier); !ok {
+		loop.ValuePattern = value
+	}
+	if loop.Key == "_" && loop.Value == "_" { //for (_, _) in xxx { block }
 		msg := fmt.Sprintf("Syntax Error:%v- foreach map's key & map are both '_'", p.curToken.Pos)
 		p.errors = append(p.errors, msg)
 		p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
@@ -1301,6 +2211,20 @@ func (p *Parser) parseStructStatement() ast.Statement {
 	p.nextToken()
 	st.Name = p.curToken.Literal
 
+	if p.peekTokenIs(token.TOKEN_COLON) { //struct Dog : Animal, Comparable { ... }
+		p.nextToken()
+		for {
+			if !p.expectPeek(token.TOKEN_IDENTIFIER) {
+				return nil
+			}
+			st.Bases = append(st.Bases, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+			if !p.peekTokenIs(token.TOKEN_COMMA) {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
 	if !p.expectPeek(token.TOKEN_LBRACE) {
 		return nil
 	}
@@ -1311,6 +2235,114 @@ func (p *Parser) parseStructStatement() ast.Statement {
 	return st
 }
 
+func (p *Parser) parseEnumStatement() ast.Statement {
+	et := &ast.EnumStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.TOKEN_IDENTIFIER) {
+		return nil
+	}
+	et.Name = p.curToken.Literal
+
+	if !p.expectPeek(token.TOKEN_LBRACE) {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.TOKEN_RBRACE) {
+		if !p.expectPeek(token.TOKEN_IDENTIFIER) {
+			return nil
+		}
+		member := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[member.Value] {
+			msg := fmt.Sprintf("Syntax Error:%v- duplicate member '%s' in enum %s", p.curToken.Pos, member.Value, et.Name)
+			p.errors = append(p.errors, msg)
+			p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+			return nil
+		}
+		seen[member.Value] = true
+
+		var value ast.Expression
+		if p.peekTokenIs(token.TOKEN_ASSIGN) { //Blue = 10
+			p.nextToken()
+			p.nextToken()
+			value = p.parseExpression(LOWEST)
+		}
+		et.Members = append(et.Members, member)
+		et.Values = append(et.Values, value)
+
+		if !p.peekTokenIs(token.TOKEN_COMMA) {
+			break
+		}
+		p.nextToken() //the trailing comma is allowed
+	}
+
+	if !p.expectPeek(token.TOKEN_RBRACE) {
+		return nil
+	}
+	et.RBraceToken = p.curToken
+
+	return et
+}
+
+func (p *Parser) parseInterfaceStatement() ast.Statement {
+	it := &ast.InterfaceStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.TOKEN_IDENTIFIER) {
+		return nil
+	}
+	it.Name = p.curToken.Literal
+
+	if !p.expectPeek(token.TOKEN_LBRACE) {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.TOKEN_RBRACE) {
+		if p.peekTokenIs(token.TOKEN_SEMICOLON) {
+			p.nextToken()
+			continue
+		}
+		if !p.expectPeek(token.TOKEN_FUNCTION) {
+			return nil
+		}
+		m := &ast.InterfaceMethod{Token: p.curToken}
+		if !p.expectPeek(token.TOKEN_IDENTIFIER) {
+			return nil
+		}
+		m.Name = p.curToken.Literal
+		if seen[m.Name] {
+			msg := fmt.Sprintf("Syntax Error:%v- duplicate method '%s' in interface %s", p.curToken.Pos, m.Name, it.Name)
+			p.errors = append(p.errors, msg)
+			p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+			return nil
+		}
+		seen[m.Name] = true
+
+		if !p.expectPeek(token.TOKEN_LPAREN) {
+			return nil
+		}
+		var values map[string]ast.Expression
+		m.Parameters, values, _, m.Variadic = p.parseFunctionParameters()
+		if m.Parameters == nil {
+			return nil
+		}
+		if values != nil {
+			msg := fmt.Sprintf("Syntax Error:%v- parameters of interface method '%s' can not have default values", p.curToken.Pos, m.Name)
+			p.errors = append(p.errors, msg)
+			p.errorLines = append(p.errorLines, p.curToken.Pos.Sline())
+			return nil
+		}
+		it.Methods = append(it.Methods, m)
+	}
+
+	if !p.expectPeek(token.TOKEN_RBRACE) {
+		return nil
+	}
+	it.RBraceToken = p.curToken
+
+	return it
+}
+
 func (p *Parser) parseSwitchExpression() ast.Expression {
 	p.fallthroughDepth++
 	switchExpr := &ast.SwitchExpression{Token: p.curToken}
@@ -1354,6 +2386,12 @@ func (p *Parser) parseSwitchExpression() ast.Expression {
 				p.nextToken() //skip comma
 				caseExpr.Exprs = append(caseExpr.Exprs, p.parseExpression(LOWEST))
 			}
+
+			if p.peekTokenIs(token.TOKEN_IF) { //case pattern if guard { block }
+				p.nextToken() //skip current token
+				p.nextToken() //skip 'if'
+				caseExpr.Guard = p.parseExpression(LOWEST)
+			}
 		} else if p.curTokenIs(token.TOKEN_DEFAULT) {
 			default_cnt++
 			if default_cnt > 1 {
@@ -1483,9 +2521,15 @@ func (p *Parser) parseThrowStatement() *ast.ThrowStmt {
 func (p *Parser) parseDecorator() ast.Expression {
 	if p.peekTokenIs(token.TOKEN_LBRACE) { //ordered hash
 		p.nextToken() //skip the '@'
-		result := p.parseHashLiteral().(*ast.HashLiteral)
-		result.IsOrdered = true
-		return result
+		switch result := p.parseHashLiteral().(type) {
+		case *ast.HashLiteral:
+			result.IsOrdered = true
+			return result
+		case *ast.HashComprehension: //e.g. @{k: v for k, v in h}
+			result.IsOrdered = true
+			return result
+		}
+		return nil
 	}
 
 	dc := &ast.DecoratorExpr{Token: p.curToken}
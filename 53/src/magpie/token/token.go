@@ -25,6 +25,18 @@ const (
 	TOKEN_SLASH_A    // /=
 	TOKEN_MOD_A      // %=
 
+	TOKEN_BITAND    // &
+	TOKEN_BITOR     // |
+	TOKEN_BITXOR    // ^
+	TOKEN_BITNOT    // ~
+	TOKEN_SHIFT_L   // <<
+	TOKEN_SHIFT_R   // >>
+	TOKEN_BITAND_A  // &=
+	TOKEN_BITOR_A   // |=
+	TOKEN_BITXOR_A  // ^=
+	TOKEN_SHIFT_L_A // <<=
+	TOKEN_SHIFT_R_A // >>=
+
 	TOKEN_LPAREN    // (
 	TOKEN_RPAREN    // )
 	TOKEN_ASSIGN    // =
@@ -32,12 +44,14 @@ const (
 	TOKEN_COLON     //:
 	TOKEN_COMMA     //,
 	TOKEN_DOT       //.
+	TOKEN_OPTDOT    //?.
 	TOKEN_DOTDOT    //..
 	TOKEN_ELLIPSIS  //...
 	TOKEN_LBRACE    // {
 	TOKEN_RBRACE    // }
 	TOKEN_BANG      // !
 	TOKEN_LBRACKET  // [
+	TOKEN_OPTINDEX  // ?[
 	TOKEN_RBRACKET  // ]
 	TOKEN_COMMENT   // #
 	TOKEN_AT        // @
@@ -57,9 +71,15 @@ const (
 	TOKEN_AND // &&
 	TOKEN_OR  // ||
 
+	TOKEN_QUESTION // ?
+	TOKEN_NULLISH  // ??
+
 	TOKEN_NUMBER     //10 or 10.1
+	TOKEN_BIGINT     //10n
+	TOKEN_DECIMAL    //10.1d
 	TOKEN_IDENTIFIER //identifier
 	TOKEN_STRING     //""
+	TOKEN_RAW_STRING //r"", """"""
 
 	//reserved keywords
 	TOKEN_TRUE        //true
@@ -87,6 +107,10 @@ const (
 	TOKEN_FINALLY     //finally
 	TOKEN_THROW       //throw
 	TOKEN_TAIL        //tail call
+	TOKEN_DEFER       //defer
+	TOKEN_CONST       //const
+	TOKEN_ENUM        //enum
+	TOKEN_INTERFACE   //interface
 
 	TOKEN_REGEX // regular expression
 )
@@ -120,6 +144,29 @@ func (tt TokenType) String() string {
 	case TOKEN_MOD_A:
 		return "%="
 
+	case TOKEN_BITAND:
+		return "&"
+	case TOKEN_BITOR:
+		return "|"
+	case TOKEN_BITXOR:
+		return "^"
+	case TOKEN_BITNOT:
+		return "~"
+	case TOKEN_SHIFT_L:
+		return "<<"
+	case TOKEN_SHIFT_R:
+		return ">>"
+	case TOKEN_BITAND_A:
+		return "&="
+	case TOKEN_BITOR_A:
+		return "|="
+	case TOKEN_BITXOR_A:
+		return "^="
+	case TOKEN_SHIFT_L_A:
+		return "<<="
+	case TOKEN_SHIFT_R_A:
+		return ">>="
+
 	case TOKEN_POWER:
 		return "**"
 	case TOKEN_INCREMENT:
@@ -140,6 +187,8 @@ func (tt TokenType) String() string {
 		return ","
 	case TOKEN_DOT:
 		return "."
+	case TOKEN_OPTDOT:
+		return "?."
 	case TOKEN_DOTDOT:
 		return ".."
 	case TOKEN_ELLIPSIS:
@@ -152,6 +201,8 @@ func (tt TokenType) String() string {
 		return "!"
 	case TOKEN_LBRACKET:
 		return "["
+	case TOKEN_OPTINDEX:
+		return "?["
 	case TOKEN_RBRACKET:
 		return "]"
 	case TOKEN_COMMENT:
@@ -186,12 +237,23 @@ func (tt TokenType) String() string {
 	case TOKEN_OR:
 		return "||"
 
+	case TOKEN_QUESTION:
+		return "?"
+	case TOKEN_NULLISH:
+		return "??"
+
 	case TOKEN_NUMBER:
 		return "NUMBER"
+	case TOKEN_BIGINT:
+		return "BIGINT"
+	case TOKEN_DECIMAL:
+		return "DECIMAL"
 	case TOKEN_IDENTIFIER:
 		return "IDENTIFIER"
 	case TOKEN_STRING:
 		return "STRING"
+	case TOKEN_RAW_STRING:
+		return "RAW_STRING"
 
 	case TOKEN_TRUE:
 		return "TRUE"
@@ -243,6 +305,14 @@ func (tt TokenType) String() string {
 		return "THROW"
 	case TOKEN_TAIL:
 		return "TAILCALL"
+	case TOKEN_DEFER:
+		return "DEFER"
+	case TOKEN_CONST:
+		return "CONST"
+	case TOKEN_ENUM:
+		return "ENUM"
+	case TOKEN_INTERFACE:
+		return "INTERFACE"
 	case TOKEN_REGEX:
 		return "<REGEX>"
 	default:
@@ -276,6 +346,10 @@ var keywords = map[string]TokenType{
 	"finally":     TOKEN_FINALLY,
 	"throw":       TOKEN_THROW,
 	"tailcall":    TOKEN_TAIL,
+	"defer":       TOKEN_DEFER,
+	"const":       TOKEN_CONST,
+	"enum":        TOKEN_ENUM,
+	"interface":   TOKEN_INTERFACE,
 }
 
 type Token struct {